@@ -0,0 +1,100 @@
+package conv
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The bulk codecs below convert whole slices between samples and bytes.
+// They avoid the reflection of encoding/binary.Read and the allocation per sample of
+// the single value functions, and have a fast path for little-endian data.
+
+// DecodeInt16s decodes len(dst) int16 values from src in the specified byte order.
+// src must hold at least 2*len(dst) bytes.
+func DecodeInt16s(dst []int16, src []byte, order binary.ByteOrder) {
+	src = src[:2*len(dst)]
+	if order == binary.LittleEndian {
+		for i := range dst {
+			dst[i] = int16(binary.LittleEndian.Uint16(src[2*i:]))
+		}
+		return
+	}
+	for i := range dst {
+		dst[i] = int16(order.Uint16(src[2*i:]))
+	}
+}
+
+// EncodeInt16s encodes src into dst in the specified byte order.
+// dst must hold at least 2*len(src) bytes.
+func EncodeInt16s(dst []byte, src []int16, order binary.ByteOrder) {
+	dst = dst[:2*len(src)]
+	if order == binary.LittleEndian {
+		for i, v := range src {
+			binary.LittleEndian.PutUint16(dst[2*i:], uint16(v))
+		}
+		return
+	}
+	for i, v := range src {
+		order.PutUint16(dst[2*i:], uint16(v))
+	}
+}
+
+// DecodeFloat32s decodes len(dst) float32 values from src in the specified byte order.
+// src must hold at least 4*len(dst) bytes.
+func DecodeFloat32s(dst []float32, src []byte, order binary.ByteOrder) {
+	src = src[:4*len(dst)]
+	if order == binary.LittleEndian {
+		for i := range dst {
+			dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(src[4*i:]))
+		}
+		return
+	}
+	for i := range dst {
+		dst[i] = math.Float32frombits(order.Uint32(src[4*i:]))
+	}
+}
+
+// EncodeFloat32s encodes src into dst in the specified byte order.
+// dst must hold at least 4*len(src) bytes.
+func EncodeFloat32s(dst []byte, src []float32, order binary.ByteOrder) {
+	dst = dst[:4*len(src)]
+	if order == binary.LittleEndian {
+		for i, v := range src {
+			binary.LittleEndian.PutUint32(dst[4*i:], math.Float32bits(v))
+		}
+		return
+	}
+	for i, v := range src {
+		order.PutUint32(dst[4*i:], math.Float32bits(v))
+	}
+}
+
+// DecodeFloat64s decodes len(dst) float64 values from src in the specified byte order.
+// src must hold at least 8*len(dst) bytes.
+func DecodeFloat64s(dst []float64, src []byte, order binary.ByteOrder) {
+	src = src[:8*len(dst)]
+	if order == binary.LittleEndian {
+		for i := range dst {
+			dst[i] = math.Float64frombits(binary.LittleEndian.Uint64(src[8*i:]))
+		}
+		return
+	}
+	for i := range dst {
+		dst[i] = math.Float64frombits(order.Uint64(src[8*i:]))
+	}
+}
+
+// EncodeFloat64s encodes src into dst in the specified byte order.
+// dst must hold at least 8*len(src) bytes.
+func EncodeFloat64s(dst []byte, src []float64, order binary.ByteOrder) {
+	dst = dst[:8*len(src)]
+	if order == binary.LittleEndian {
+		for i, v := range src {
+			binary.LittleEndian.PutUint64(dst[8*i:], math.Float64bits(v))
+		}
+		return
+	}
+	for i, v := range src {
+		order.PutUint64(dst[8*i:], math.Float64bits(v))
+	}
+}
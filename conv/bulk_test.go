@@ -0,0 +1,125 @@
+package conv
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var byteOrders = []struct {
+	name  string
+	order binary.ByteOrder
+}{
+	{"LittleEndian", binary.LittleEndian},
+	{"BigEndian", binary.BigEndian},
+}
+
+func TestBulkRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	i16s := []int16{0, 1, -1, math.MaxInt16, math.MinInt16}
+	f32s := []float32{0, float32(math.Copysign(0, -1)), 1, -1, math.MaxFloat32, math.SmallestNonzeroFloat32,
+		float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.NaN())}
+	f64s := []float64{0, math.Copysign(0, -1), 1, -1, math.MaxFloat64, math.SmallestNonzeroFloat64,
+		math.Inf(1), math.Inf(-1), math.NaN()}
+	for i := 0; i < 1000; i++ {
+		i16s = append(i16s, int16(rng.Intn(1<<16)-1<<15))
+		f32s = append(f32s, math.Float32frombits(rng.Uint32()))
+		f64s = append(f64s, math.Float64frombits(rng.Uint64()))
+	}
+
+	for _, bo := range byteOrders {
+		t.Run(bo.name, func(t *testing.T) {
+			b := make([]byte, 2*len(i16s))
+			EncodeInt16s(b, i16s, bo.order)
+			if got := bo.order.Uint16(b[2:]); got != uint16(i16s[1]) {
+				t.Errorf("EncodeInt16s: second value is %#04x, want %#04x", got, uint16(i16s[1]))
+			}
+			gotI16s := make([]int16, len(i16s))
+			DecodeInt16s(gotI16s, b, bo.order)
+			for i := range i16s {
+				if gotI16s[i] != i16s[i] {
+					t.Fatalf("int16 %d: got %d, want %d", i, gotI16s[i], i16s[i])
+				}
+			}
+
+			b = make([]byte, 4*len(f32s))
+			EncodeFloat32s(b, f32s, bo.order)
+			gotF32s := make([]float32, len(f32s))
+			DecodeFloat32s(gotF32s, b, bo.order)
+			for i := range f32s {
+				if math.Float32bits(gotF32s[i]) != math.Float32bits(f32s[i]) {
+					t.Fatalf("float32 %d: got %#08x, want %#08x", i, math.Float32bits(gotF32s[i]), math.Float32bits(f32s[i]))
+				}
+			}
+
+			b = make([]byte, 8*len(f64s))
+			EncodeFloat64s(b, f64s, bo.order)
+			gotF64s := make([]float64, len(f64s))
+			DecodeFloat64s(gotF64s, b, bo.order)
+			for i := range f64s {
+				if math.Float64bits(gotF64s[i]) != math.Float64bits(f64s[i]) {
+					t.Fatalf("float64 %d: got %#016x, want %#016x", i, math.Float64bits(gotF64s[i]), math.Float64bits(f64s[i]))
+				}
+			}
+		})
+	}
+}
+
+// benchmarkSamples is the number of samples of the benchmarks, 1 second at 48 kHz.
+const benchmarkSamples = 48000
+
+func BenchmarkDecode(b *testing.B) {
+	for _, bo := range byteOrders {
+		src := make([]byte, 8*benchmarkSamples)
+		rand.New(rand.NewSource(1)).Read(src)
+		b.Run("Int16s/"+bo.name, func(b *testing.B) {
+			dst := make([]int16, benchmarkSamples)
+			b.SetBytes(2 * benchmarkSamples)
+			for i := 0; i < b.N; i++ {
+				DecodeInt16s(dst, src, bo.order)
+			}
+		})
+		b.Run("Float32s/"+bo.name, func(b *testing.B) {
+			dst := make([]float32, benchmarkSamples)
+			b.SetBytes(4 * benchmarkSamples)
+			for i := 0; i < b.N; i++ {
+				DecodeFloat32s(dst, src, bo.order)
+			}
+		})
+		b.Run("Float64s/"+bo.name, func(b *testing.B) {
+			dst := make([]float64, benchmarkSamples)
+			b.SetBytes(8 * benchmarkSamples)
+			for i := 0; i < b.N; i++ {
+				DecodeFloat64s(dst, src, bo.order)
+			}
+		})
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, bo := range byteOrders {
+		dst := make([]byte, 8*benchmarkSamples)
+		b.Run("Int16s/"+bo.name, func(b *testing.B) {
+			src := make([]int16, benchmarkSamples)
+			b.SetBytes(2 * benchmarkSamples)
+			for i := 0; i < b.N; i++ {
+				EncodeInt16s(dst, src, bo.order)
+			}
+		})
+		b.Run("Float32s/"+bo.name, func(b *testing.B) {
+			src := make([]float32, benchmarkSamples)
+			b.SetBytes(4 * benchmarkSamples)
+			for i := 0; i < b.N; i++ {
+				EncodeFloat32s(dst, src, bo.order)
+			}
+		})
+		b.Run("Float64s/"+bo.name, func(b *testing.B) {
+			src := make([]float64, benchmarkSamples)
+			b.SetBytes(8 * benchmarkSamples)
+			for i := 0; i < b.N; i++ {
+				EncodeFloat64s(dst, src, bo.order)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package conv
+
+// Sample is the constraint of the sample types of DXX data.
+type Sample interface {
+	int16 | float32 | float64
+}
+
+type sampleKind int
+
+const (
+	kindInt16 sampleKind = iota
+	kindFloat32
+	kindFloat64
+)
+
+func kindOf[T Sample]() sampleKind {
+	var zero T
+	switch any(zero).(type) {
+	case int16:
+		return kindInt16
+	case float32:
+		return kindFloat32
+	default:
+		return kindFloat64
+	}
+}
+
+// Abs returns the absolute value of x.
+func Abs[T Sample](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// AbsSlice returns the absolute values of data.
+func AbsSlice[T Sample](data []T) []T {
+	ret := make([]T, len(data))
+	for i, v := range data {
+		ret[i] = Abs(v)
+	}
+	return ret
+}
+
+// Min returns the minimum value of data. It returns 0 for empty data.
+func Min[T Sample](data []T) T {
+	min, _ := MinMax(data)
+	return min
+}
+
+// Max returns the maximum value of data. It returns 0 for empty data.
+func Max[T Sample](data []T) T {
+	_, max := MinMax(data)
+	return max
+}
+
+// MinMax returns the minimum and maximum values of data. It returns 0, 0 for empty data.
+func MinMax[T Sample](data []T) (min, max T) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	min, max = data[0], data[0]
+	for _, v := range data[1:] {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, max
+}
+
+// AbsMinMax returns the absolute values of data and their minimum and maximum.
+func AbsMinMax[T Sample](data []T) (absData []T, min, max T) {
+	absData = AbsSlice(data)
+	min, max = MinMax(absData)
+	return absData, min, max
+}
+
+// MaxAbs returns the peak absolute value of data.
+// The result is float64 because the absolute value of math.MinInt16 does not fit in int16.
+func MaxAbs[T Sample](data []T) float64 {
+	var peak float64
+	for _, v := range data {
+		a := float64(v)
+		if a < 0 {
+			a = -a
+		}
+		if a > peak {
+			peak = a
+		}
+	}
+	return peak
+}
+
+// Convert converts data to another sample type without changing the values.
+// Values converted to int16 are rounded and saturated.
+// It is equivalent to Scale with a Scaler in Preserve mode.
+func Convert[From, To Sample](data []From) []To {
+	return Scale[From, To](&Scaler{}, data)
+}
@@ -1,3 +1,3 @@
 module github.com/tetsuzawa/go-soundlib/conv
 
-go 1.15
+go 1.18
@@ -0,0 +1,99 @@
+package conv
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Quantizer quantizes scaled samples to integer values.
+// Quantizers with dither or noise shaping keep state between samples,
+// so a Quantizer must not be shared between independent signals.
+type Quantizer interface {
+	// Quantize returns v quantized to an integer value.
+	// The result is not saturated to the range of the destination type.
+	Quantize(v float64) float64
+}
+
+// Truncate quantizes by truncating toward zero, like the conversion int16(v).
+type Truncate struct{}
+
+// Quantize returns v truncated toward zero.
+func (Truncate) Quantize(v float64) float64 {
+	return math.Trunc(v)
+}
+
+// Round quantizes to the nearest integer. This is the default of Scaler.
+type Round struct{}
+
+// Quantize returns v rounded to the nearest integer.
+func (Round) Quantize(v float64) float64 {
+	return math.Round(v)
+}
+
+// TPDFDither adds triangular probability density function dither of ±1 LSB before rounding.
+// The dither decorrelates the quantisation error from the signal.
+type TPDFDither struct {
+	rng *rand.Rand
+}
+
+// NewTPDFDither returns a TPDFDither whose random numbers are generated from seed.
+// The same seed gives the same output.
+func NewTPDFDither(seed int64) *TPDFDither {
+	return &TPDFDither{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Quantize returns v plus dither rounded to the nearest integer.
+func (d *TPDFDither) Quantize(v float64) float64 {
+	return math.Round(v + tpdf(d.rng))
+}
+
+// tpdf returns a random number in (-1, 1) with triangular distribution.
+func tpdf(rng *rand.Rand) float64 {
+	return rng.Float64() - rng.Float64()
+}
+
+// Error feedback filters of NoiseShaper.
+// The noise transfer function is 1 - Σ c[k] z^-(k+1).
+var (
+	// ShapeFirstOrder is the first-order highpass shaping (1 - z^-1).
+	ShapeFirstOrder = []float64{1}
+	// ShapeLipshitz is the 5-tap psychoacoustically weighted filter by Lipshitz et al.
+	// It is designed for 44.1 kHz and works similarly at 48 kHz.
+	ShapeLipshitz = []float64{2.033, -2.165, 1.959, -1.590, 0.6149}
+	// ShapeFWeighted is the 9-tap F-weighted filter by Wannamaker.
+	// It is designed for 44.1 kHz and works similarly at 48 kHz.
+	ShapeFWeighted = []float64{2.412, -3.370, 3.937, -4.174, 3.353, -2.205, 1.281, -0.569, 0.0847}
+)
+
+// NoiseShaper adds TPDF dither and feeds back the quantisation error through a FIR filter,
+// which moves the quantisation noise to the frequencies where it is less audible.
+type NoiseShaper struct {
+	coeffs []float64
+	// errs holds the previous quantisation errors. errs[0] is the latest one.
+	errs []float64
+	rng  *rand.Rand
+}
+
+// NewNoiseShaper returns a NoiseShaper with the error feedback coefficients.
+// The random numbers of the dither are generated from seed.
+func NewNoiseShaper(coeffs []float64, seed int64) *NoiseShaper {
+	return &NoiseShaper{
+		coeffs: append([]float64(nil), coeffs...),
+		errs:   make([]float64, len(coeffs)),
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Quantize returns v with the shaped error and dither, rounded to the nearest integer.
+func (s *NoiseShaper) Quantize(v float64) float64 {
+	w := v
+	for k, c := range s.coeffs {
+		w -= c * s.errs[k]
+	}
+	q := math.Round(w + tpdf(s.rng))
+	if len(s.errs) > 0 {
+		copy(s.errs[1:], s.errs)
+		s.errs[0] = q - w
+	}
+	return q
+}
@@ -0,0 +1,160 @@
+package conv
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestTPDFDitherWithinOneLSB(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// the dither is triangular in (-1, 1) with a variance of 1/6
+	const n = 200000
+	var sum, sumSq float64
+	for i := 0; i < n; i++ {
+		d := tpdf(rng)
+		if d <= -1 || d >= 1 {
+			t.Fatalf("dither %v is outside (-1, 1)", d)
+		}
+		sum += d
+		sumSq += d * d
+	}
+	if mean := sum / n; math.Abs(mean) > 0.01 {
+		t.Errorf("mean of the dither is %v, want 0", mean)
+	}
+	if v := sumSq / n; math.Abs(v-1.0/6) > 0.01 {
+		t.Errorf("variance of the dither is %v, want 1/6", v)
+	}
+
+	// the output is at most 1 LSB from the rounded input, and the error does not depend on the input
+	q := NewTPDFDither(1)
+	for _, v := range []float64{0, 0.25, 0.5, -0.3, 1000.75, -12345.5} {
+		var sum float64
+		for i := 0; i < n/10; i++ {
+			got := q.Quantize(v)
+			if got != math.Trunc(got) {
+				t.Fatalf("Quantize(%v) = %v is not an integer", v, got)
+			}
+			if d := math.Abs(got - math.Round(v)); d > 1 {
+				t.Fatalf("Quantize(%v) = %v is %v LSB from the rounded input", v, got, d)
+			}
+			sum += got - v
+		}
+		if mean := sum / (n / 10); math.Abs(mean) > 0.02 {
+			t.Errorf("mean error of Quantize(%v) is %v, want 0", v, mean)
+		}
+	}
+}
+
+func TestTPDFDitherSeed(t *testing.T) {
+	a, b := NewTPDFDither(7), NewTPDFDither(7)
+	for i := 0; i < 1000; i++ {
+		v := float64(i) / 3
+		if qa, qb := a.Quantize(v), b.Quantize(v); qa != qb {
+			t.Fatalf("sample %d: %v and %v from the same seed", i, qa, qb)
+		}
+	}
+}
+
+// noiseTransfer returns the power gain |1 - Σ c[k] z^-(k+1)|^2 of the noise transfer function at the normalised frequency f.
+func noiseTransfer(coeffs []float64, f float64) float64 {
+	h := complex(1, 0)
+	for k, c := range coeffs {
+		h -= complex(c, 0) * cmplx.Exp(complex(0, -2*math.Pi*f*float64(k+1)))
+	}
+	return real(h)*real(h) + imag(h)*imag(h)
+}
+
+// errorSpectrum returns the power spectrum of the error of q on a low-level sine, in bins of 1/frame,
+// averaged over Hann-windowed frames. White error of variance σ² has the level σ².
+func errorSpectrum(q Quantizer, frame, frames int) []float64 {
+	window := make([]float64, frame)
+	var norm float64
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frame))
+		norm += window[i] * window[i]
+	}
+	spec := make([]float64, frame/2+1)
+	e := make([]float64, frame)
+	n := 0
+	for m := 0; m < frames; m++ {
+		for i := range e {
+			v := 100.3 * math.Sin(2*math.Pi*0.0123*float64(n))
+			e[i] = (q.Quantize(v) - v) * window[i]
+			n++
+		}
+		for k := range spec {
+			var x complex128
+			for i, v := range e {
+				x += complex(v, 0) * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i)/float64(frame)))
+			}
+			spec[k] += real(x)*real(x) + imag(x)*imag(x)
+		}
+	}
+	for k := range spec {
+		spec[k] /= norm * float64(frames)
+	}
+	return spec
+}
+
+func TestNoiseShaperSpectrum(t *testing.T) {
+	const frame, frames = 128, 800
+	// the error of the quantiser inside the loop is the rounding error and the TPDF dither, of variance 1/12 + 1/6
+	const level = 0.25
+	bands := [][2]float64{{0.01, 0.1}, {0.1, 0.2}, {0.2, 0.3}, {0.3, 0.4}, {0.4, 0.49}}
+	for _, c := range []struct {
+		name   string
+		coeffs []float64
+	}{
+		{"FirstOrder", ShapeFirstOrder},
+		{"Lipshitz", ShapeLipshitz},
+		{"FWeighted", ShapeFWeighted},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			spec := errorSpectrum(NewNoiseShaper(c.coeffs, 1), frame, frames)
+			for _, b := range bands {
+				var got, want float64
+				bins := 0
+				for k := int(math.Ceil(b[0] * frame)); float64(k) <= b[1]*frame; k++ {
+					got += spec[k]
+					want += level * noiseTransfer(c.coeffs, float64(k)/frame)
+					bins++
+				}
+				got, want = got/float64(bins), want/float64(bins)
+				// in dB, as the shaped noise spans a wide range of levels
+				if d := 10 * math.Log10(got/want); math.Abs(d) > 1.5 {
+					t.Errorf("band %v of fs: error power %.3g, want %.3g (%+.1f dB)", b, got, want, d)
+				}
+			}
+		})
+	}
+
+	// without shaping, the error is white at the level of the dithered rounding
+	spec := errorSpectrum(NewTPDFDither(1), frame, frames)
+	for _, b := range bands {
+		var got float64
+		bins := 0
+		for k := int(math.Ceil(b[0] * frame)); float64(k) <= b[1]*frame; k++ {
+			got += spec[k]
+			bins++
+		}
+		if d := 10 * math.Log10(got/float64(bins)/level); math.Abs(d) > 1 {
+			t.Errorf("TPDFDither: band %v of fs: error power %.3g, want %.3g", b, got/float64(bins), level)
+		}
+	}
+}
+
+func TestFirstOrderShapingNoErrorAtDC(t *testing.T) {
+	// first-order shaping has no error at DC: the error is the difference of the errors
+	// of the rounding in the loop, which are within ±1.5, so its running sum stays within ±3.
+	q := NewNoiseShaper(ShapeFirstOrder, 1)
+	var sum float64
+	for i := 0; i < 100000; i++ {
+		v := 0.37 + 1000*math.Sin(float64(i)/100)
+		sum += q.Quantize(v) - v
+		if math.Abs(sum) > 3 {
+			t.Fatalf("sample %d: the error accumulated to %v", i, sum)
+		}
+	}
+}
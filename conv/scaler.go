@@ -0,0 +1,177 @@
+package conv
+
+import (
+	"math"
+)
+
+// FullScaleInt16 is the value of the full scale of int16 in Q15 format.
+const FullScaleInt16 = 1 << 15
+
+// ScaleMode is the amplitude policy of Scaler.
+type ScaleMode int
+
+const (
+	// Preserve keeps the values as they are. e.g. int16(1000) <-> 1000.0
+	Preserve ScaleMode = iota
+	// FullScale maps the full scale of int16 to [-1, 1) as Q15. e.g. int16(16384) <-> 0.5
+	// Conversions between float32 and float64 keep the values.
+	FullScale
+	// Peak scales the values so that the peak absolute value of each converted slice equals Scaler.Target.
+	Peak
+	// Gain multiplies the values by the gain of Scaler.GainDB.
+	Gain
+)
+
+// String returns the scale mode name as string.
+func (m ScaleMode) String() string {
+	switch m {
+	case Preserve:
+		return "preserve"
+	case FullScale:
+		return "fullscale"
+	case Peak:
+		return "peak"
+	case Gain:
+		return "gain"
+	default:
+		return "unknown scale mode"
+	}
+}
+
+// Scaler converts samples between int16, float32 and float64 with an explicit amplitude policy.
+// Unlike the package level conversion functions, Scaler keeps the level relationships between
+// slices unless Peak mode is used.
+// Values which exceed the range of the destination type are saturated and counted in Clipped.
+// The zero value is a Scaler in Preserve mode.
+type Scaler struct {
+	Mode ScaleMode
+	// Target is the peak absolute value after scaling in Peak mode.
+	Target float64
+	// GainDB is the gain in Gain mode [dB].
+	GainDB float64
+	// Quantizer quantizes the values converted to int16. The default is Round.
+	Quantizer Quantizer
+	// Clipped is the number of samples saturated by the conversions so far.
+	Clipped int
+}
+
+// factor returns the factor to multiply the values by.
+// fullScale is the factor of FullScale mode and peak is the peak absolute value of the source.
+func (s *Scaler) factor(fullScale, peak float64) float64 {
+	switch s.Mode {
+	case FullScale:
+		return fullScale
+	case Peak:
+		if peak == 0 {
+			return 1
+		}
+		return s.Target / peak
+	case Gain:
+		return math.Pow(10, s.GainDB/20)
+	default:
+		return 1
+	}
+}
+
+// toInt16 quantizes v and saturates it to the range of int16.
+func (s *Scaler) toInt16(v float64) int16 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	if s.Quantizer != nil {
+		v = s.Quantizer.Quantize(v)
+	} else {
+		v = math.Round(v)
+	}
+	if v > math.MaxInt16 {
+		s.Clipped++
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		s.Clipped++
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+// toFloat32 saturates finite v to the range of float32.
+func (s *Scaler) toFloat32(v float64) float32 {
+	if v > math.MaxFloat32 && !math.IsInf(v, 1) {
+		s.Clipped++
+		return math.MaxFloat32
+	}
+	if v < -math.MaxFloat32 && !math.IsInf(v, -1) {
+		s.Clipped++
+		return -math.MaxFloat32
+	}
+	return float32(v)
+}
+
+// Scale converts data to another sample type as specified by the Scaler.
+func Scale[From, To Sample](s *Scaler, data []From) []To {
+	fullScale := 1.0
+	switch from, to := kindOf[From](), kindOf[To](); {
+	case from == kindInt16 && to != kindInt16:
+		fullScale = 1.0 / FullScaleInt16
+	case from != kindInt16 && to == kindInt16:
+		fullScale = FullScaleInt16
+	}
+	var peak float64
+	if s.Mode == Peak {
+		peak = MaxAbs(data)
+	}
+	k := s.factor(fullScale, peak)
+
+	ret := make([]To, len(data))
+	switch kindOf[To]() {
+	case kindInt16:
+		for i, v := range data {
+			ret[i] = To(s.toInt16(float64(v) * k))
+		}
+	case kindFloat32:
+		for i, v := range data {
+			ret[i] = To(s.toFloat32(float64(v) * k))
+		}
+	default:
+		for i, v := range data {
+			ret[i] = To(float64(v) * k)
+		}
+	}
+	return ret
+}
+
+// Float64sToInt16s converts float64 samples to int16.
+func (s *Scaler) Float64sToInt16s(data []float64) []int16 {
+	return Scale[float64, int16](s, data)
+}
+
+// Float32sToInt16s converts float32 samples to int16.
+func (s *Scaler) Float32sToInt16s(data []float32) []int16 {
+	return Scale[float32, int16](s, data)
+}
+
+// Int16sToFloat64s converts int16 samples to float64.
+func (s *Scaler) Int16sToFloat64s(data []int16) []float64 {
+	return Scale[int16, float64](s, data)
+}
+
+// Int16sToFloat32s converts int16 samples to float32.
+func (s *Scaler) Int16sToFloat32s(data []int16) []float32 {
+	return Scale[int16, float32](s, data)
+}
+
+// Float32sToFloat64s converts float32 samples to float64.
+func (s *Scaler) Float32sToFloat64s(data []float32) []float64 {
+	return Scale[float32, float64](s, data)
+}
+
+// Float64sToFloat32s converts float64 samples to float32.
+func (s *Scaler) Float64sToFloat32s(data []float64) []float32 {
+	return Scale[float64, float32](s, data)
+}
+
+// Float64s scales float64 samples.
+// The returned slice is always a copy.
+func (s *Scaler) Float64s(data []float64) []float64 {
+	return Scale[float64, float64](s, data)
+}
@@ -3,6 +3,7 @@ package conv
 import (
 	"bytes"
 	"encoding/binary"
+	"math"
 )
 
 func BytesToFloat64(b []byte) (float64, error) {
@@ -44,13 +45,45 @@ func Int16ToBytes(v int16) ([]byte, error) {
 	return buf.Bytes(), err
 }
 
+// DecodeInt16 decodes an int16 from the first 2 bytes of b in the specified byte order.
+func DecodeInt16(b []byte, order binary.ByteOrder) int16 {
+	return int16(order.Uint16(b))
+}
+
+// EncodeInt16 encodes v into the first 2 bytes of b in the specified byte order.
+func EncodeInt16(b []byte, v int16, order binary.ByteOrder) {
+	order.PutUint16(b, uint16(v))
+}
+
+// DecodeFloat32 decodes a float32 from the first 4 bytes of b in the specified byte order.
+func DecodeFloat32(b []byte, order binary.ByteOrder) float32 {
+	return math.Float32frombits(order.Uint32(b))
+}
+
+// EncodeFloat32 encodes v into the first 4 bytes of b in the specified byte order.
+func EncodeFloat32(b []byte, v float32, order binary.ByteOrder) {
+	order.PutUint32(b, math.Float32bits(v))
+}
+
+// DecodeFloat64 decodes a float64 from the first 8 bytes of b in the specified byte order.
+func DecodeFloat64(b []byte, order binary.ByteOrder) float64 {
+	return math.Float64frombits(order.Uint64(b))
+}
+
+// EncodeFloat64 encodes v into the first 8 bytes of b in the specified byte order.
+func EncodeFloat64(b []byte, v float64, order binary.ByteOrder) {
+	order.PutUint64(b, math.Float64bits(v))
+}
+
+// Float32sToInt16s normalises data by its peak absolute value to an amplitude of 32767.
+// Use Scaler to keep the level of the data.
 func Float32sToInt16s(data []float32) []int16 {
 	const amp = 1<<(16-1) - 1
-	_, min, max := AbsMinMaxFloat32s(data)
+	peak := float32(MaxAbs(data))
 
 	ret := make([]int16, len(data))
 	for i, v := range data {
-		vv := int16((AbsFloat32(v) - min) / (max - min) * amp)
+		vv := int16(AbsFloat32(v) / peak * amp)
 		if v < 0 {
 			vv = -vv
 		}
@@ -59,13 +92,15 @@ func Float32sToInt16s(data []float32) []int16 {
 	return ret
 }
 
+// Float64sToInt16s normalises data by its peak absolute value to an amplitude of 32767.
+// Use Scaler to keep the level of the data.
 func Float64sToInt16s(data []float64) []int16 {
 	const amp = 1<<(16-1) - 1 // default amp for .DSX
-	_, min, max := AbsMinMaxFloat64s(data)
+	peak := MaxAbs(data)
 
 	ret := make([]int16, len(data))
 	for i, v := range data {
-		vv := int16((AbsFloat64(v) - min) / (max - min) * amp)
+		vv := int16(AbsFloat64(v) / peak * amp)
 		if v < 0 {
 			vv = -vv
 		}
@@ -74,13 +109,15 @@ func Float64sToInt16s(data []float64) []int16 {
 	return ret
 }
 
+// Int16sToFloat32s normalises data by its peak absolute value to an amplitude of 10000.
+// Use Scaler to keep the level of the data.
 func Int16sToFloat32s(data []int16) []float32 {
 	const amp = 10000.0 // default amp for .DFX
-	_, min, max := AbsMinMaxInt16s(data)
+	peak := float32(MaxAbs(data))
 
 	ret := make([]float32, len(data))
 	for i, v := range data {
-		vv := float32(AbsInt16(v)-min) / float32(max-min) * amp
+		vv := float32(AbsInt16(v)) / peak * amp
 		if v < 0 {
 			vv = -vv
 		}
@@ -89,132 +126,102 @@ func Int16sToFloat32s(data []int16) []float32 {
 	return ret
 }
 
+// Int16sToFloat64s normalises data by its peak absolute value to an amplitude of 10000.
+// Use Scaler to keep the level of the data.
 func Int16sToFloat64s(data []int16) []float64 {
 	const amp = 10000.0 // default amp for .DDX
-	_, min, max := AbsMinMaxInt16s(data)
+	peak := MaxAbs(data)
 
 	ret := make([]float64, len(data))
 	for i, v := range data {
-		vv := float64(AbsInt16(v)-min) / float64(max-min) * amp
-		ret[i] = vv
+		vv := float64(AbsInt16(v)) / peak * amp
 		if v < 0 {
 			vv = -vv
 		}
+		ret[i] = vv
 	}
 	return ret
 }
 
+// Float32sToFloat64s normalises data by its peak absolute value to an amplitude of 10000.
+// Use Scaler to keep the level of the data.
 func Float32sToFloat64s(data []float32) []float64 {
 	const amp = 10000.0 // default amp for .DDX
-	_, min, max := AbsMinMaxFloat32s(data)
-
+	peak := MaxAbs(data)
 
 	ret := make([]float64, len(data))
 	for i, v := range data {
-		vv := float64(AbsFloat32(v)-min) / float64(max-min) * amp
-		ret[i] = vv
+		vv := float64(AbsFloat32(v)) / peak * amp
 		if v < 0 {
 			vv = -vv
 		}
+		ret[i] = vv
 	}
 	return ret
 }
 
+// Float64sToFloat32s normalises data by its peak absolute value to an amplitude of 10000.
+// Use Scaler to keep the level of the data.
 func Float64sToFloat32s(data []float64) []float32 {
 	const amp = 10000.0 // default amp for .DDX
-	_, min, max := AbsMinMaxFloat64s(data)
+	peak := MaxAbs(data)
 
 	ret := make([]float32, len(data))
 	for i, v := range data {
-		vv := float32((AbsFloat64(v) - min) / (max - min) * amp)
-		ret[i] = vv
+		vv := float32(AbsFloat64(v) / peak * amp)
 		if v < 0 {
 			vv = -vv
 		}
+		ret[i] = vv
 	}
 	return ret
 }
 
-func AbsInt16(x int16) int16 {
-	if x < 0 {
-		return -x
-	} else {
-		return x
-	}
-}
+// The functions below are kept for compatibility.
+// Abs* are instances of the generic functions Abs and AbsSlice.
+// Max*, Min* and AbsMinMax* keep their original results, which differ from the generic Max, Min and AbsMinMax:
+// the maximum and the minimum start from 0, so MaxXs returns 0 for negative data and MinXs returns 0 for positive data,
+// and the minimum returned by AbsMinMaxXs is 0 unless an int16 is math.MinInt16, whose absolute value overflows.
 
-func AbsFloat32(x float32) float32 {
-	if x < 0 {
-		return -x
-	} else {
-		return x
-	}
-}
+func AbsInt16(x int16) int16 { return Abs(x) }
 
-func AbsFloat64(x float64) float64 {
-	if x < 0 {
-		return -x
-	} else {
-		return x
-	}
-}
+func AbsFloat32(x float32) float32 { return Abs(x) }
 
-func AbsInt16s(data []int16) []int16 {
-	ret := make([]int16, len(data))
-	for i, v := range data {
-		ret[i] = AbsInt16(v)
-	}
-	return ret
-}
+func AbsFloat64(x float64) float64 { return Abs(x) }
 
-func AbsFloat32s(data []float32) []float32 {
-	ret := make([]float32, len(data))
-	for i, v := range data {
-		ret[i] = AbsFloat32(v)
-	}
-	return ret
-}
+func AbsInt16s(data []int16) []int16 { return AbsSlice(data) }
 
-func AbsFloat64s(data []float64) []float64 {
-	ret := make([]float64, len(data))
-	for i, v := range data {
-		ret[i] = AbsFloat64(v)
-	}
-	return ret
-}
+func AbsFloat32s(data []float32) []float32 { return AbsSlice(data) }
 
-func AbsMinMaxInt16s(data []int16) (absData []int16, min, max int16) {
-	absData = make([]int16, len(data))
-	for i, v := range data {
-		vAbs := AbsInt16(v)
-		absData[i] = vAbs
-		if vAbs < min {
-			min = vAbs
-		} else if max < vAbs {
-			max = vAbs
-		}
-	}
-	return absData, min, max
-}
+func AbsFloat64s(data []float64) []float64 { return AbsSlice(data) }
+
+func AbsMinMaxInt16s(data []int16) (absData []int16, min, max int16) { return absMinMaxFromZero(data) }
 
 func AbsMinMaxFloat32s(data []float32) (absData []float32, min, max float32) {
-	absData = make([]float32, len(data))
-	for i, v := range data {
-		vAbs := AbsFloat32(v)
-		absData[i] = vAbs
-		if vAbs < min {
-			min = vAbs
-		} else if max < vAbs {
-			max = vAbs
-		}
-	}
-	return absData, min, max
+	return absMinMaxFromZero(data)
 }
 
 func AbsMinMaxFloat64s(data []float64) (absData []float64, min, max float64) {
-	absData = make([]float64, len(data))
+	return absMinMaxFromZero(data)
+}
+
+func MaxInt16s(data []int16) int16 { return maxFromZero(data) }
+
+func MinInt16s(data []int16) int16 { return minFromZero(data) }
+
+func MaxFloat32s(data []float32) float32 { return maxFromZero(data) }
+
+func MinFloat32s(data []float32) float32 { return minFromZero(data) }
+
+func MaxFloat64s(data []float64) float64 { return maxFromZero(data) }
+
+func MinFloat64s(data []float64) float64 { return minFromZero(data) }
+
+// absMinMaxFromZero is the original AbsMinMaxXs.
+func absMinMaxFromZero[T Sample](data []T) (absData []T, min, max T) {
+	absData = make([]T, len(data))
 	for i, v := range data {
-		vAbs := AbsFloat64(v)
+		vAbs := Abs(v)
 		absData[i] = vAbs
 		if vAbs < min {
 			min = vAbs
@@ -225,48 +232,9 @@ func AbsMinMaxFloat64s(data []float64) (absData []float64, min, max float64) {
 	return absData, min, max
 }
 
-func MaxInt16s(data []int16) int16 {
-	var max int16
-	for _, v := range data {
-		if v > max {
-			max = v
-		}
-	}
-	return max
-}
-
-func MinInt16s(data []int16) int16 {
-	var min int16
-	for _, v := range data {
-		if v < min {
-			min = v
-		}
-	}
-	return min
-}
-
-func MaxFloat32s(data []float32) float32 {
-	var max float32
-	for _, v := range data {
-		if v > max {
-			max = v
-		}
-	}
-	return max
-}
-
-func MinFloat32s(data []float32) float32 {
-	var min float32
-	for _, v := range data {
-		if v < min {
-			min = v
-		}
-	}
-	return min
-}
-
-func MaxFloat64s(data []float64) float64 {
-	var max float64
+// maxFromZero is the original MaxXs.
+func maxFromZero[T Sample](data []T) T {
+	var max T
 	for _, v := range data {
 		if v > max {
 			max = v
@@ -275,8 +243,9 @@ func MaxFloat64s(data []float64) float64 {
 	return max
 }
 
-func MinFloat64s(data []float64) float64 {
-	var min float64
+// minFromZero is the original MinXs.
+func minFromZero[T Sample](data []T) T {
+	var min T
 	for _, v := range data {
 		if v < min {
 			min = v
@@ -0,0 +1,61 @@
+package conv
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLegacyMinMax pins the results of the original Max*, Min* and AbsMinMax*, which start from 0.
+func TestLegacyMinMax(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"MaxInt16s(negative)", float64(MaxInt16s([]int16{-3, -1})), 0},
+		{"MaxInt16s", float64(MaxInt16s([]int16{-3, 7, 2})), 7},
+		{"MinInt16s(positive)", float64(MinInt16s([]int16{2, 5})), 0},
+		{"MinInt16s", float64(MinInt16s([]int16{2, -5})), -5},
+		{"MaxFloat32s(negative)", float64(MaxFloat32s([]float32{-0.5, -0.25})), 0},
+		{"MinFloat32s(positive)", float64(MinFloat32s([]float32{0.5, 0.25})), 0},
+		{"MaxFloat64s(negative)", MaxFloat64s([]float64{-0.5, -0.25}), 0},
+		{"MaxFloat64s(NaN)", MaxFloat64s([]float64{math.NaN(), 1}), 1},
+		{"MinFloat64s(positive)", MinFloat64s([]float64{0.5, 0.25}), 0},
+		{"MinFloat64s", MinFloat64s([]float64{0.5, -0.25}), -0.25},
+		{"MaxFloat64s(empty)", MaxFloat64s(nil), 0},
+		{"MinFloat64s(empty)", MinFloat64s(nil), 0},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	abs, min, max := AbsMinMaxFloat64s([]float64{-3, 2, -5})
+	if min != 0 || max != 5 || abs[0] != 3 || abs[1] != 2 || abs[2] != 5 {
+		t.Errorf("AbsMinMaxFloat64s([-3 2 -5]) = %v, %v, %v, want [3 2 5], 0, 5", abs, min, max)
+	}
+	if _, min, max := AbsMinMaxFloat32s([]float32{-0.5, 0.25}); min != 0 || max != 0.5 {
+		t.Errorf("AbsMinMaxFloat32s([-0.5 0.25]) = _, %v, %v, want 0, 0.5", min, max)
+	}
+	if _, min, max := AbsMinMaxInt16s([]int16{4, -9}); min != 0 || max != 9 {
+		t.Errorf("AbsMinMaxInt16s([4 -9]) = _, %v, %v, want 0, 9", min, max)
+	}
+	// the absolute value of math.MinInt16 overflows
+	if _, min, max := AbsMinMaxInt16s([]int16{math.MinInt16, 1}); min != math.MinInt16 || max != 1 {
+		t.Errorf("AbsMinMaxInt16s([-32768 1]) = _, %v, %v, want -32768, 1", min, max)
+	}
+}
+
+func TestGenericMinMax(t *testing.T) {
+	if got := Max([]int16{-3, -1}); got != -1 {
+		t.Errorf("Max([-3 -1]) = %v, want -1", got)
+	}
+	if got := Min([]float64{2, 5}); got != 2 {
+		t.Errorf("Min([2 5]) = %v, want 2", got)
+	}
+	if _, min, max := AbsMinMax([]float64{-3, 2, -5}); min != 2 || max != 5 {
+		t.Errorf("AbsMinMax([-3 2 -5]) = _, %v, %v, want 2, 5", min, max)
+	}
+	if min, max := MinMax([]float32(nil)); min != 0 || max != 0 {
+		t.Errorf("MinMax(nil) = %v, %v, want 0, 0", min, max)
+	}
+}
@@ -0,0 +1,135 @@
+package dxx
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// AppendFile is a DXX file opened for appending samples by OpenAppend.
+// Samples are written by the embedded Writer, which is buffered:
+// call Sync to make them durable, and Close when done.
+type AppendFile struct {
+	*Writer
+	f *os.File
+	// n is the number of samples in the file including the written ones.
+	n int
+}
+
+// OpenAppend opens the .DXX file for appending samples, creating it if it does not exist.
+// This func determines the data type from the filename extension.
+// The existing content is validated: binary data must be a whole number of samples,
+// and ASCII data must parse. A newline is added to ASCII data which does not end with one.
+// Compressed files cannot be appended to.
+func OpenAppend(filename string) (*AppendFile, error) {
+	dt, err := StringToDataType(ext(filename))
+	if err != nil {
+		return nil, err
+	}
+	if c, _ := CompressionOf(filename); c != NoCompression {
+		return nil, fmt.Errorf("%s: cannot append to %s compressed data", filename, c)
+	}
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	n, err := validateAppend(f, dt)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &AppendFile{Writer: NewWriter(f, dt), f: f, n: n}, nil
+}
+
+// validateAppend returns the number of samples in f and moves the offset of f to its end.
+func validateAppend(f *os.File, dt DataType) (int, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if dt.IsBinary() {
+		if size%int64(dt.ByteLen()) != 0 {
+			return 0, ErrTruncated
+		}
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return 0, err
+		}
+		return int(size / int64(dt.ByteLen())), nil
+	}
+
+	data, err := Read(f, dt, -1)
+	if err != nil {
+		return 0, err
+	}
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			return 0, err
+		}
+		if last[0] != '\n' {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(data), nil
+}
+
+// WriteSamples appends data to the file.
+// It returns the number of samples written and any error encountered.
+func (f *AppendFile) WriteSamples(data []float64) (int, error) {
+	n, err := f.Writer.WriteSamples(data)
+	f.n += n
+	return n, err
+}
+
+// Len returns the number of samples in the file, including the samples written but not yet flushed.
+func (f *AppendFile) Len() int {
+	return f.n
+}
+
+// Sync flushes the written samples and commits them to the disk.
+func (f *AppendFile) Sync() error {
+	if err := f.Flush(); err != nil {
+		return err
+	}
+	return f.f.Sync()
+}
+
+// Truncate discards the samples after the first n, e.g. those written after the last checkpoint
+// of an interrupted process. Only binary data can be truncated.
+func (f *AppendFile) Truncate(n int) error {
+	if !f.dt.IsBinary() {
+		return ErrNotBinary
+	}
+	if n < 0 || n > f.n {
+		return ErrOutOfRange
+	}
+	if err := f.Flush(); err != nil {
+		return err
+	}
+	size := int64(n) * int64(f.dt.ByteLen())
+	if err := f.f.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := f.f.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	f.n = n
+	return nil
+}
+
+// Close flushes the written samples and closes the file.
+func (f *AppendFile) Close() error {
+	err := f.Flush()
+	if cerr := f.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Name returns the name of the file.
+func (f *AppendFile) Name() string {
+	return f.f.Name()
+}
@@ -0,0 +1,85 @@
+package dxx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CommentPrefix is the prefix of comment lines in ASCII DXX data.
+const CommentPrefix = "#"
+
+// ParseError describes a line of ASCII DXX data which could not be parsed.
+type ParseError struct {
+	// File is the name of the file. It is empty when the data is not read from a file.
+	File string
+	// Line is the 1-based line number.
+	Line int
+	// Text is the content of the line.
+	Text string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: cannot parse %q: %v", e.Line, e.Text, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: cannot parse %q: %v", e.File, e.Line, e.Text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// asciiScanner reads the values of ASCII DXX data line by line.
+// Blank lines and comment lines are skipped, and both LF and CRLF line endings are accepted.
+type asciiScanner struct {
+	sc   *bufio.Scanner
+	dt   DataType
+	line int
+}
+
+func newASCIIScanner(r io.Reader, dt DataType) *asciiScanner {
+	return &asciiScanner{sc: bufio.NewScanner(r), dt: dt}
+}
+
+// next returns the next value. At the end of data, next returns io.EOF.
+func (s *asciiScanner) next() (float64, error) {
+	for s.sc.Scan() {
+		s.line++
+		text := strings.TrimSpace(s.sc.Text())
+		if text == "" || strings.HasPrefix(text, CommentPrefix) {
+			continue
+		}
+
+		var (
+			v   float64
+			err error
+		)
+		switch s.dt {
+		case DSA:
+			var i int64
+			i, err = strconv.ParseInt(text, 10, 16)
+			v = float64(i)
+		case DFA:
+			v, err = strconv.ParseFloat(text, 32)
+		default:
+			v, err = strconv.ParseFloat(text, 64)
+		}
+		if err != nil {
+			// strconv.NumError repeats the text, so only its cause is kept.
+			if ne, ok := err.(*strconv.NumError); ok {
+				err = ne.Err
+			}
+			return 0, &ParseError{Line: s.line, Text: s.sc.Text(), Err: err}
+		}
+		return v, nil
+	}
+	if err := s.sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
@@ -0,0 +1,152 @@
+package dxx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile is a file which appears at its name only when it is completely written.
+// The data is written to a temporary file in the same directory, which is synced to the disk
+// and renamed to the name by Commit, or removed by Abort.
+// A failed or interrupted write never leaves a partial file at the name.
+type AtomicFile struct {
+	*os.File
+	name        string
+	noOverwrite bool
+	done        bool
+}
+
+// CreateAtomic creates a temporary file to be committed to filename.
+// If opts.NoOverwrite is set, Commit fails if filename exists. The other options are not used.
+// The file has the permissions of the file it replaces, or 0666 less the umask for a new file, as os.Create gives.
+func CreateAtomic(filename string, opts *Options) (*AtomicFile, error) {
+	noOverwrite := opts != nil && opts.NoOverwrite
+	info, err := os.Stat(filename)
+	if err == nil && noOverwrite {
+		return nil, &fs.PathError{Op: "create", Path: filename, Err: fs.ErrExist}
+	}
+
+	f, err := createTemp(filename)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		if err := f.Chmod(info.Mode().Perm()); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, err
+		}
+	}
+	return &AtomicFile{File: f, name: filename, noOverwrite: noOverwrite}, nil
+}
+
+// createTemp creates a new temporary file for filename in its directory.
+// Unlike os.CreateTemp, which creates it with 0600, it is created with 0666 so that the umask applies.
+func createTemp(filename string) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	for try := 0; ; try++ {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(prefix+hex.EncodeToString(b[:])+".tmp", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrExist) && try < 100 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// Commit syncs the written data to the disk and moves the file to its name.
+// If Commit fails, the temporary file is removed.
+func (f *AtomicFile) Commit() (err error) {
+	if f.done {
+		return fs.ErrClosed
+	}
+	f.done = true
+	tmp := f.File.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if f.noOverwrite {
+		// unlike rename, link fails if the name has been created since CreateAtomic.
+		if err := os.Link(tmp, f.name); err != nil {
+			return err
+		}
+		os.Remove(tmp)
+	} else if err := os.Rename(tmp, f.name); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(f.name))
+	return nil
+}
+
+// Abort discards the file. It is a no-op after Commit, so it can be deferred.
+func (f *AtomicFile) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
+
+// Name returns the name the file is committed to.
+func (f *AtomicFile) Name() string {
+	return f.name
+}
+
+// syncDir syncs the directory so that the rename survives a crash.
+// Errors are ignored because some platforms and file systems cannot sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// writeFileAtomic writes a file by write as an AtomicFile.
+// If the file name has a compression extension, the data is compressed, recording pred and the byte order of opts.
+func writeFileAtomic(filename string, opts *Options, pred Predictor, write func(w io.Writer) error) error {
+	f, err := CreateAtomic(filename, opts)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	c, _ := CompressionOf(filename)
+	if c == NoCompression {
+		if err := write(f); err != nil {
+			return err
+		}
+		return f.Commit()
+	}
+	zw, err := compressWriter(f, c, pred, opts.byteOrder())
+	if err != nil {
+		return err
+	}
+	if err := write(zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Commit()
+}
@@ -0,0 +1,114 @@
+package dxx
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkFile checks that the directory of filename holds only filename, with the content want.
+func checkFile(t *testing.T, filename, want string) {
+	t.Helper()
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("%s: %q, want %q", filepath.Base(filename), b, want)
+	}
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != filepath.Base(filename) {
+			t.Errorf("%s is left in the directory", e.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	errWrite := errors.New("write failed")
+	for _, name := range []string{"a.DSB", "a.DSB.gz", "a.DSB.zlib", "a.DSB.flate"} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(filename, []byte("original"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			err := writeFileAtomic(filename, nil, NoPredictor, func(w io.Writer) error {
+				if _, err := w.Write(make([]byte, 1<<16)); err != nil {
+					return err
+				}
+				return errWrite
+			})
+			if err != errWrite {
+				t.Fatalf("writeFileAtomic: got %v, want %v", err, errWrite)
+			}
+			checkFile(t, filename, "original")
+
+			// a complete write replaces the file, keeping its permissions
+			if err := writeFileAtomic(filename, nil, NoPredictor, func(w io.Writer) error {
+				_, err := w.Write([]byte{1, 2})
+				return err
+			}); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("permissions %v, want %v", info.Mode().Perm(), fs.FileMode(0600))
+			}
+		})
+	}
+}
+
+func TestAtomicFileAbort(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.DDB")
+	if err := os.WriteFile(filename, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateAtomic(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filename, "original")
+	if err := f.Commit(); err != fs.ErrClosed {
+		t.Errorf("Commit after Abort: got %v, want fs.ErrClosed", err)
+	}
+}
+
+func TestAtomicFileNoOverwrite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.DDB")
+	opts := &Options{NoOverwrite: true}
+	f, err := CreateAtomic(filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Abort()
+	if _, err := f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	// the file is created by someone else before Commit
+	if err := os.WriteFile(filename, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Commit(); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Commit: got %v, want fs.ErrExist", err)
+	}
+	checkFile(t, filename, "original")
+
+	if _, err := CreateAtomic(filename, opts); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("CreateAtomic of an existing file: got %v, want fs.ErrExist", err)
+	}
+}
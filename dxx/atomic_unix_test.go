@@ -0,0 +1,49 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package dxx
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestCreateAtomicUmask checks that a new file has the permissions os.Create gives under the umask,
+// and that a replaced file keeps its permissions.
+func TestCreateAtomicUmask(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(027))
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name string
+		// perm is the permissions of the existing file, or 0 for a new file
+		perm, want fs.FileMode
+	}{
+		{"new.DDB", 0, 0640},
+		{"new.DDB.gz", 0, 0640},
+		{"replaced.DDB", 0604, 0604},
+	} {
+		filename := filepath.Join(dir, c.name)
+		if c.perm != 0 {
+			if err := os.WriteFile(filename, []byte("original"), c.perm); err != nil {
+				t.Fatal(err)
+			}
+			// the umask restricts the permissions WriteFile creates the file with
+			if err := os.Chmod(filename, c.perm); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteToFile(filename, []float64{1, 2}); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != c.want {
+			t.Errorf("%s: permissions %v, want %v", c.name, info.Mode().Perm(), c.want)
+		}
+	}
+}
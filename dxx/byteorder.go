@@ -0,0 +1,33 @@
+package dxx
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrNotBinary = errors.New("data type is not binary")
+)
+
+// Magnitudes outside of this range are implausible for floating-point samples.
+const (
+	minPlausibleMagnitude = 1e-20
+	maxPlausibleMagnitude = 1e20
+)
+
+// DetectByteOrder guesses the byte order of binary DXX data from its content.
+// It decodes b in both byte orders and chooses the one whose samples look more like a signal,
+// by the same rules as Detect.
+// For DFB and DDB, the samples must be finite and of plausible magnitude.
+// For DSB, the samples must be close to the previous sample,
+// because swapping the bytes of a smooth signal turns its low bytes into large jumps.
+// If both orders are equally plausible DSB, e.g. a quiet signal whose swapped bytes still make small steps,
+// DetectByteOrder returns the order whose samples vary less in total, as a smooth signal does.
+// In any other tie, including DSB of equal variation, it returns binary.LittleEndian.
+func DetectByteOrder(b []byte, dt DataType) (binary.ByteOrder, error) {
+	if !dt.IsBinary() {
+		return nil, ErrNotBinary
+	}
+	_, order := scoreBinary(b, dt)
+	return order, nil
+}
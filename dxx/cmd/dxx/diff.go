@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// errDiffer is returned by diff when the signals differ. The report has already been printed.
+var errDiffer = errors.New("signals differ")
+
+func runDiff(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, false)
+	var opts dxx.CompareOptions
+	fs.Float64Var(&opts.Tolerance, "tol", 0, "largest absolute difference at which samples are regarded as equal")
+	fs.BoolVar(&opts.Align, "align", false, "align b to a by cross-correlation before comparing")
+	fs.IntVar(&opts.MaxLag, "maxlag", dxx.DefaultMaxLag, "largest lag searched by -align [samples]")
+	quiet := fs.Bool("q", false, "print nothing, only set the exit status")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 2, 2); err != nil {
+		return err
+	}
+	a, err := readSignal(fs.Arg(0), f.inType)
+	if err != nil {
+		return err
+	}
+	b, err := readSignal(fs.Arg(1), f.inType)
+	if err != nil {
+		return err
+	}
+	bdata := b.data
+	if k := levelFactor(b.format, a.format); k != 1 {
+		bdata = scale(bdata, k)
+	}
+
+	d := dxx.CompareWithOptions(a.data, bdata, &opts)
+	if !*quiet {
+		tw := tabwriter.NewWriter(stdout, 0, 8, 1, ' ', 0)
+		fmt.Fprintf(tw, "a:\t%s (%s, %d samples)\n", fs.Arg(0), a.format, d.LenA)
+		fmt.Fprintf(tw, "b:\t%s (%s, %d samples)\n", fs.Arg(1), b.format, d.LenB)
+		if opts.Align {
+			fmt.Fprintf(tw, "lag:\t%d samples\n", d.Lag)
+		}
+		fmt.Fprintf(tw, "compared:\t%d samples\n", d.Compared)
+		if d.MaxAbsErrIndex >= 0 {
+			fmt.Fprintf(tw, "max error:\t%g at sample %d\n", d.MaxAbsErr, d.MaxAbsErrIndex)
+		}
+		fmt.Fprintf(tw, "rms error:\t%g\n", d.RMSErr)
+		fmt.Fprintf(tw, "snr:\t%g dB\n", d.SNR)
+		if d.FirstDiff >= 0 {
+			fmt.Fprintf(tw, "first diff:\tsample %d\n", d.FirstDiff)
+		}
+		switch {
+		case d.LenA != d.LenB:
+			fmt.Fprintf(tw, "result:\tdiffer in length\n")
+		case !d.Equal():
+			fmt.Fprintf(tw, "result:\tdiffer beyond tolerance %g\n", opts.Tolerance)
+		default:
+			fmt.Fprintf(tw, "result:\tequal within tolerance %g\n", opts.Tolerance)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if !d.Equal() {
+		return errDiffer
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+)
+
+func runCat(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, false)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 1, -1); err != nil {
+		return err
+	}
+	for _, name := range fs.Args() {
+		s, err := readSignal(name, f.inType)
+		if err != nil {
+			return err
+		}
+		if err := writeASCII(stdout, s.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runHead(fs *flag.FlagSet, args []string) error {
+	return runDump(fs, args, func(data []float64, n int) []float64 {
+		if n < len(data) {
+			return data[:n]
+		}
+		return data
+	})
+}
+
+func runTail(fs *flag.FlagSet, args []string) error {
+	return runDump(fs, args, func(data []float64, n int) []float64 {
+		if n < len(data) {
+			return data[len(data)-n:]
+		}
+		return data
+	})
+}
+
+// runDump prints the part of the samples of a file chosen by pick.
+func runDump(fs *flag.FlagSet, args []string, pick func(data []float64, n int) []float64) error {
+	var f ioFlags
+	f.register(fs, false)
+	n := fs.Int("n", 10, "number of samples")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 1, 1); err != nil {
+		return err
+	}
+	if *n < 0 {
+		return errUsage
+	}
+	s, err := readSignal(fs.Arg(0), f.inType)
+	if err != nil {
+		return err
+	}
+	return writeASCII(stdout, pick(s.data, *n))
+}
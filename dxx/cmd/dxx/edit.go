@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+func runConvert(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, true)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 2, 2); err != nil {
+		return err
+	}
+	s, err := readSignal(fs.Arg(0), f.inType)
+	if err != nil {
+		return err
+	}
+	return convertSignal(fs.Arg(1), s, s.data, &f)
+}
+
+func runCut(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, true)
+	start := fs.Int("start", 0, "first sample to write. Negative values count from the end")
+	n := fs.Int("n", -1, "number of samples to write. Negative means to the end")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 2, 2); err != nil {
+		return err
+	}
+	s, err := readSignal(fs.Arg(0), f.inType)
+	if err != nil {
+		return err
+	}
+
+	from := *start
+	if from < 0 {
+		from += len(s.data)
+	}
+	if from < 0 || from > len(s.data) {
+		return fmt.Errorf("start %d is out of range of %d samples", *start, len(s.data))
+	}
+	to := len(s.data)
+	if *n >= 0 && from+*n < to {
+		to = from + *n
+	}
+	return convertSignal(fs.Arg(1), s, s.data[from:to], &f)
+}
+
+func runConcat(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, true)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 2, -1); err != nil {
+		return err
+	}
+	srcs, dst := fs.Args()[:fs.NArg()-1], fs.Arg(fs.NArg()-1)
+
+	var (
+		first *signal
+		data  []float64
+	)
+	for _, name := range srcs {
+		s, err := readSignal(name, f.inType)
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = s
+		}
+		if first.fs > 0 && s.fs > 0 && s.fs != first.fs {
+			return fmt.Errorf("%s: sampling rate %d Hz differs from %d Hz", name, s.fs, first.fs)
+		}
+		// the sources are joined at the level of the first one.
+		k := levelFactor(s.format, first.format)
+		for _, v := range s.data {
+			data = append(data, v*k)
+		}
+	}
+	return convertSignal(dst, first, data, &f)
+}
+
+func runGain(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, true)
+	db := fs.Float64("db", 0, "gain [dB]")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 2, 2); err != nil {
+		return err
+	}
+	s, err := readSignal(fs.Arg(0), f.inType)
+	if err != nil {
+		return err
+	}
+	return convertSignal(fs.Arg(1), s, scale(s.data, math.Pow(10, *db/20)), &f)
+}
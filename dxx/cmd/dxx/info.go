@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"text/tabwriter"
+)
+
+func runInfo(fs *flag.FlagSet, args []string) error {
+	var f ioFlags
+	f.register(fs, false)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := nargs(fs, 1, -1); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 8, 1, ' ', 0)
+	for i, name := range fs.Args() {
+		s, err := readSignal(name, f.inType)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		st := stats(s.data)
+		rate := f.samplingRate(s)
+		fmt.Fprintf(tw, "file:\t%s\n", name)
+		fmt.Fprintf(tw, "format:\t%s (%s)\n", s.format, s.source)
+		fmt.Fprintf(tw, "samples:\t%d\n", len(s.data))
+		fmt.Fprintf(tw, "duration:\t%g s at %d Hz\n", float64(len(s.data))/float64(rate), rate)
+		fmt.Fprintf(tw, "peak:\t%g at sample %d\n", st.peak, st.peakIndex)
+		fmt.Fprintf(tw, "rms:\t%g\n", st.rms)
+		fmt.Fprintf(tw, "dc:\t%g\n", st.dc)
+		fmt.Fprintf(tw, "nan:\t%d\n", st.nan)
+	}
+	return tw.Flush()
+}
+
+// statistics are the level statistics of a signal. NaNs are excluded.
+type statistics struct {
+	peak      float64
+	peakIndex int
+	rms       float64
+	dc        float64
+	nan       int
+}
+
+func stats(data []float64) statistics {
+	var (
+		st      statistics
+		sum, sq float64
+	)
+	st.peakIndex = -1
+	for i, v := range data {
+		if math.IsNaN(v) {
+			st.nan++
+			continue
+		}
+		if a := math.Abs(v); st.peakIndex < 0 || a > st.peak {
+			st.peak, st.peakIndex = a, i
+		}
+		sum += v
+		sq += v * v
+	}
+	if n := len(data) - st.nan; n > 0 {
+		st.dc = sum / float64(n)
+		st.rms = math.Sqrt(sq / float64(n))
+	}
+	return st
+}
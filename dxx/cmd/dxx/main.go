@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// command is a subcommand of dxx.
+type command struct {
+	usage string
+	help  string
+	run   func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"info":    {"info [flags] file...", "print the type, length, duration, peak, RMS, DC and NaN count of the files", runInfo},
+	"convert": {"convert [flags] src dst", "convert src to the type of dst, including WAV and CSV", runConvert},
+	"cat":     {"cat [flags] file...", "print the samples of the files as ASCII, one per line", runCat},
+	"head":    {"head [flags] file", "print the first samples of the file as ASCII", runHead},
+	"tail":    {"tail [flags] file", "print the last samples of the file as ASCII", runTail},
+	"cut":     {"cut [flags] src dst", "write a range of the samples of src to dst", runCut},
+	"diff":    {"diff [flags] a b", "compare the samples of b with a, and exit with status 1 if they differ", runDiff},
+	"concat":  {"concat [flags] src... dst", "write the samples of the sources one after another to dst", runConcat},
+	"gain":    {"gain [flags] src dst", "multiply the samples of src by a gain and write them to dst", runGain},
+}
+
+// stdin and stdout are the standard streams of the commands. The tests replace them.
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+)
+
+func init() {
+	log.SetFlags(0)
+}
+
+// usage prints the usage of dxx.
+func usage() {
+	log.Printf("Usage: %s command [flags] args\n", filepath.Base(os.Args[0]))
+	log.Printf("reads and writes mono .DXX files. \"-\" as a file name means stdin or stdout.\n")
+	log.Printf("the exit status is 2 on errors, and 1 if diff finds differences.\n\n")
+	log.Printf("Commands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("  %-8s %s\n", name, commands[name].help)
+	}
+	log.Printf("\nRun '%s command -h' for the flags of the command.\n", filepath.Base(os.Args[0]))
+}
+
+func main() {
+	switch err := run(os.Args[1:]); {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errDiffer):
+		os.Exit(1)
+	default:
+		os.Exit(2)
+	}
+}
+
+// run runs the command of the arguments. The errors are printed to the log, with the usage if the arguments are invalid.
+func run(args []string) error {
+	top := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	top.Usage = usage
+	if err := top.Parse(args); err != nil {
+		return err
+	}
+	if top.NArg() == 0 {
+		usage()
+		return errUsage
+	}
+	name := top.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		log.Printf("error: unknown command: %s\n\n", name)
+		usage()
+		return fmt.Errorf("%w: unknown command %s", errUsage, name)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		log.Printf("Usage: %s %s\n", filepath.Base(os.Args[0]), cmd.usage)
+		log.Printf("%s.\n", cmd.help)
+		fs.PrintDefaults()
+	}
+	err := cmd.run(fs, top.Args()[1:])
+	var fe *flagError
+	if err == nil || errors.Is(err, errDiffer) || errors.As(err, &fe) {
+		return err
+	}
+	log.Printf("error: %+v\n\n", err)
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+	}
+	return err
+}
+
+// flagError is an error of parsing the flags of a command, which the flag package has printed with the usage.
+type flagError struct {
+	err error
+}
+
+func (e *flagError) Error() string {
+	return e.err.Error()
+}
+
+func (e *flagError) Unwrap() error {
+	return e.err
+}
+
+// parseFlags parses the flags of a command.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return &flagError{err}
+	}
+	return nil
+}
+
+var errUsage = errors.New("invalid arguments")
+
+// nargs checks the number of the arguments of a command.
+func nargs(fs *flag.FlagSet, min, max int) error {
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		return fmt.Errorf("%w: %d arguments", errUsage, fs.NArg())
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// testRun runs dxx with the arguments and the input, and returns its output. The log is discarded.
+func testRun(t *testing.T, input string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	defer func(r io.Reader, w io.Writer, l io.Writer) {
+		stdin, stdout = r, w
+		log.SetOutput(l)
+	}(stdin, stdout, log.Writer())
+	stdin, stdout = strings.NewReader(input), &out
+	log.SetOutput(io.Discard)
+	err := run(args)
+	return out.String(), err
+}
+
+// writeStored writes the values as they are to the DXX file name.
+func writeStored(t *testing.T, name string, values []float64, m *dxx.Meta) {
+	t.Helper()
+	if err := dxx.WriteToFileWithOptions(name, values, &dxx.Options{Scaler: &conv.Scaler{}}); err != nil {
+		t.Fatal(err)
+	}
+	if m != nil {
+		if err := dxx.WriteMeta(name, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readStored reads the values stored in the file name.
+func readStored(t *testing.T, name string) []float64 {
+	t.Helper()
+	data, err := dxx.ReadFromFileWithOptions(name, &dxx.Options{Scaler: &conv.Scaler{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func assertSame(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %v, want %v", name, got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s: %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.DSB")
+	writeStored(t, src, []float64{16384, -8192, 0, 32767}, &dxx.Meta{SamplingRate: 44100, Units: "Pa"})
+
+	for _, c := range []struct {
+		dst  string
+		args []string
+		want []float64
+	}{
+		{"out.DDA", nil, []float64{16384, -8192, 0, 32767}},
+		{"out.DFB.gz", nil, []float64{16384, -8192, 0, 32767}},
+		// DSX samples are Q15 in WAV
+		{"out.wav", nil, []float64{0.5, -0.25, 0, 32767.0 / 32768}},
+		{"out.DDB", []string{"-T", "ddb", "-predictor", "delta"}, []float64{16384, -8192, 0, 32767}},
+	} {
+		dst := filepath.Join(dir, c.dst)
+		if _, err := testRun(t, "", append(append([]string{"convert"}, c.args...), src, dst)...); err != nil {
+			t.Fatalf("convert to %s: %v", c.dst, err)
+		}
+		data, m, err := dxx.ReadFileWithMetaFSWithOptions(os.DirFS(dir), c.dst, &dxx.Options{Scaler: &conv.Scaler{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSame(t, c.dst, data, c.want)
+		if m == nil || m.SamplingRate != 44100 {
+			t.Errorf("%s: metadata %+v, want 44100 Hz", c.dst, m)
+		}
+	}
+
+	// -fs overrides the recorded sampling rate
+	dst := filepath.Join(dir, "fs.DSB")
+	if _, err := testRun(t, "", "convert", "-fs", "8000", src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := dxx.ReadMeta(dst); err != nil || m == nil || m.SamplingRate != 8000 || m.Units != "Pa" {
+		t.Errorf("convert -fs 8000: metadata %+v, %v", m, err)
+	}
+
+	// CSV with a header, from stdin
+	dst = filepath.Join(dir, "csv.DDB")
+	if _, err := testRun(t, "time,value\n0.5,1\n-1.5,2\n", "convert", "-t", "csv", "-", dst); err != nil {
+		t.Fatal(err)
+	}
+	assertSame(t, "CSV", readStored(t, dst), []float64{0.5, -1.5})
+
+	// to stdout
+	out, err := testRun(t, "", "convert", "-T", "DSA", src, "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "16384\n-8192\n0\n32767\n" {
+		t.Errorf("convert to stdout: %q", out)
+	}
+}
+
+func TestDump(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.DDB")
+	writeStored(t, a, []float64{0.5, -1, 2, 3}, nil)
+	b := filepath.Join(dir, "b.DFB")
+	writeStored(t, b, []float64{4}, nil)
+
+	for _, c := range []struct {
+		input string
+		args  []string
+		want  string
+	}{
+		{"", []string{"cat", a, b}, "0.5\n-1\n2\n3\n4\n"},
+		{"", []string{"head", "-n", "2", a}, "0.5\n-1\n"},
+		{"", []string{"tail", "-n", "3", a}, "-1\n2\n3\n"},
+		{"", []string{"tail", "-n", "10", b}, "4\n"},
+		{"# comment\n1\n2.5\n", []string{"cat", "-t", "DDA", "-"}, "1\n2.5\n"},
+	} {
+		out, err := testRun(t, c.input, c.args...)
+		if err != nil {
+			t.Fatalf("%q: %v", c.args, err)
+		}
+		if out != c.want {
+			t.Errorf("%q: printed %q, want %q", c.args, out, c.want)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.DDB")
+	writeStored(t, name, []float64{1, -3, 2, 0}, &dxx.Meta{SamplingRate: 8000})
+	out, err := testRun(t, "", "info", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"format: DDB (extension)",
+		"samples: 4",
+		"duration: 0.0005 s at 8000 Hz",
+		"peak: 3 at sample 1",
+		"dc: 0",
+		"nan: 0",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(out), " "), want) {
+			t.Errorf("info has no %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.DDB")
+	writeStored(t, a, []float64{1, 2, 3, 4, 5}, &dxx.Meta{SamplingRate: 8000})
+	b := filepath.Join(dir, "b.DSB")
+	writeStored(t, b, []float64{16384}, nil)
+
+	for _, c := range []struct {
+		args []string
+		want []float64
+	}{
+		{[]string{"cut", "-start", "1", "-n", "2"}, []float64{2, 3}},
+		{[]string{"cut", "-start", "-2"}, []float64{4, 5}},
+		{[]string{"gain", "-db", "20"}, []float64{10, 20, 30, 40, 50}},
+	} {
+		dst := filepath.Join(dir, "out.DDB")
+		if _, err := testRun(t, "", append(c.args, a, dst)...); err != nil {
+			t.Fatalf("%q: %v", c.args, err)
+		}
+		assertSame(t, strings.Join(c.args, " "), readStored(t, dst), c.want)
+	}
+	if _, err := testRun(t, "", "cut", "-start", "6", a, filepath.Join(dir, "out.DDB")); err == nil {
+		t.Error("cut from beyond the end succeeded")
+	}
+
+	// joined at the level of the first source
+	wav := filepath.Join(dir, "a.wav")
+	if _, err := testRun(t, "", "convert", b, wav); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testRun(t, "", "concat", "-T", "DSB", b, wav, filepath.Join(dir, "concat.DSB")); err != nil {
+		t.Fatal(err)
+	}
+	assertSame(t, "concat", readStored(t, filepath.Join(dir, "concat.DSB")), []float64{16384, 16384})
+	// sampling rates which differ
+	c := filepath.Join(dir, "c.DDB")
+	writeStored(t, c, []float64{6}, &dxx.Meta{SamplingRate: 44100})
+	if _, err := testRun(t, "", "concat", a, c, filepath.Join(dir, "concat.DDB")); err == nil || !strings.Contains(err.Error(), "44100 Hz differs from 8000 Hz") {
+		t.Errorf("concat of 8000 Hz and 44100 Hz: got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.DDB")
+	writeStored(t, a, []float64{1, 2, 3}, nil)
+	b := filepath.Join(dir, "b.DDA")
+	writeStored(t, b, []float64{1, 2, 3.001}, nil)
+
+	out, err := testRun(t, "", "diff", a, b)
+	if !errors.Is(err, errDiffer) {
+		t.Fatalf("diff of different signals: got %v, want errDiffer", err)
+	}
+	if !strings.Contains(out, "first diff: sample 2") || !strings.Contains(out, "differ beyond tolerance") {
+		t.Errorf("diff printed:\n%s", out)
+	}
+	if out, err := testRun(t, "", "diff", "-tol", "0.01", a, b); err != nil || !strings.Contains(out, "equal within tolerance 0.01") {
+		t.Errorf("diff -tol 0.01: got %v:\n%s", err, out)
+	}
+	if out, err := testRun(t, "", "diff", "-q", a, b); !errors.Is(err, errDiffer) || out != "" {
+		t.Errorf("diff -q: got %v and printed %q", err, out)
+	}
+}
+
+func TestMultichannel(t *testing.T) {
+	dir := t.TempDir()
+	wav := filepath.Join(dir, "stereo.wav")
+	var buf bytes.Buffer
+	if err := dxx.WriteWAV(&buf, dxx.WAVFormat{SampleRate: 48000, Channels: 2, BitsPerSample: 16}, []float64{0, 0.5, 0, 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(wav, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	interleaved := filepath.Join(dir, "stereo.DDB")
+	if err := dxx.WriteChannelsToFile(interleaved, [][]float64{{0, 0}, {0.5, 0.5}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{wav, interleaved} {
+		for _, args := range [][]string{
+			{"info", name},
+			{"convert", name, filepath.Join(dir, "mono.DDB")},
+		} {
+			_, err := testRun(t, "", args...)
+			if !errors.Is(err, errMultichannel) || !strings.Contains(err.Error(), "2") {
+				t.Errorf("%q: got %v, want errMultichannel of 2 channels", args, err)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mono.DDB")); !os.IsNotExist(err) {
+		t.Errorf("a multichannel signal was converted: %v", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.DDB")
+	writeStored(t, name, []float64{1}, nil)
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"info"},
+		{"head", name, name},
+		{"head", "-n", "-1", name},
+		{"convert", name},
+	} {
+		if _, err := testRun(t, "", args...); !errors.Is(err, errUsage) {
+			t.Errorf("%q: got %v, want errUsage", args, err)
+		}
+	}
+	var fe *flagError
+	if _, err := testRun(t, "", "cat", "-x", name); !errors.As(err, &fe) {
+		t.Errorf("an unknown flag: got %v, want a *flagError", err)
+	}
+	for _, args := range [][]string{{"-h"}, {"info", "-h"}} {
+		if _, err := testRun(t, "", args...); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("%q: got %v, want flag.ErrHelp", args, err)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// The formats besides the DXX data types.
+const (
+	formatWAV = "WAV"
+	formatCSV = "CSV"
+)
+
+// defaultSamplingRate is used when the sampling rate is neither given nor recorded.
+const defaultSamplingRate = 48000
+
+// errMultichannel is returned for a signal of more than one channel, which the commands do not handle.
+var errMultichannel = errors.New("only mono signals are supported")
+
+// signal is a mono signal read by the commands.
+type signal struct {
+	data []float64
+	// format is a DXX data type, formatWAV or formatCSV.
+	format string
+	// how the format was determined, e.g. "extension"
+	source string
+	// order is the byte order of binary DXX data.
+	order binary.ByteOrder
+	// fs is the sampling rate [Hz]. It is 0 if unknown.
+	fs   int
+	meta *dxx.Meta
+}
+
+// ioFlags are the flags common to the commands which read and write signals.
+type ioFlags struct {
+	inType  string
+	outType string
+	fs      int
+	bits    int
+	pcm     bool
+	pred    string
+	verb    string
+	prec    int
+}
+
+func (f *ioFlags) register(fs *flag.FlagSet, out bool) {
+	fs.StringVar(&f.inType, "t", "", "format of the input: DSA, DFA, DDA, DSB, DFB, DDB, WAV or CSV (default: by extension or content)")
+	fs.IntVar(&f.fs, "fs", 0, "sampling rate [Hz] (default: from the WAV header or the metadata sidecar, else 48000)")
+	if !out {
+		return
+	}
+	fs.StringVar(&f.outType, "T", "", "format of the output (default: by extension, else the format of the input)")
+	fs.IntVar(&f.bits, "bits", 32, "bits per sample of WAV output: 16, 24 or 32 for PCM, 32 or 64 for float")
+	fs.BoolVar(&f.pcm, "pcm", false, "write WAV output as integer PCM instead of IEEE float")
+	fs.StringVar(&f.pred, "predictor", "none", "predictor of compressed binary DXX output: none, delta or xor")
+	fs.StringVar(&f.verb, "fmt", "g", "format verb of DFA and DDA output: e, E, f, g or G")
+	fs.IntVar(&f.prec, "prec", 0, "digits of DFA and DDA output (default: the fewest which read back exactly)")
+}
+
+// options returns the options to write DXX output with.
+func (f *ioFlags) options() (*dxx.Options, error) {
+	pred, err := dxx.StringToPredictor(f.pred)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.verb) != 1 {
+		return nil, fmt.Errorf("%w: %q", dxx.ErrInvalidFormat, f.verb)
+	}
+	// the commands keep the stored values and convert the levels between the formats by levelFactor.
+	return &dxx.Options{Scaler: &conv.Scaler{}, Predictor: pred, Format: f.verb[0], Precision: f.prec}, nil
+}
+
+// samplingRate returns the sampling rate to use for s.
+func (f *ioFlags) samplingRate(s *signal) int {
+	switch {
+	case f.fs > 0:
+		return f.fs
+	case s.fs > 0:
+		return s.fs
+	default:
+		return defaultSamplingRate
+	}
+}
+
+// parseFormat validates the format name given by a flag or an extension.
+func parseFormat(s string) (string, error) {
+	s = strings.ToUpper(s)
+	if s == formatWAV || s == formatCSV {
+		return s, nil
+	}
+	if _, err := dxx.StringToDataType(s); err != nil {
+		return "", fmt.Errorf("unknown format: %q", s)
+	}
+	return s, nil
+}
+
+// formatOf returns the format given by the extension of name, ignoring a compression extension.
+// It returns "" if the extension is not a format.
+func formatOf(name string) string {
+	_, name = dxx.CompressionOf(name)
+	f, err := parseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+	if err != nil {
+		return ""
+	}
+	return f
+}
+
+// readSignal reads the file name, or stdin if name is "-".
+// The format is typ if not empty, else the extension, else detected from the content.
+// Compressed files are read by their extension.
+func readSignal(name, typ string) (*signal, error) {
+	if c, _ := dxx.CompressionOf(name); c != dxx.NoCompression && name != "-" {
+		return readCompressed(name, typ)
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if name == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &signal{format: typ, source: "flag", order: binary.LittleEndian}
+	if s.format == "" {
+		s.format, s.source = formatOf(name), "extension"
+	}
+	if s.format == "" {
+		d, err := dxx.Detect(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		s.format, s.source = d.DataType.String(), fmt.Sprintf("content, confidence %.2f", d.Confidence)
+		if d.ByteOrder != nil {
+			s.order = d.ByteOrder
+		}
+	}
+	if s.format, err = parseFormat(s.format); err != nil {
+		return nil, err
+	}
+
+	switch s.format {
+	case formatWAV:
+		var f dxx.WAVFormat
+		if s.data, f, err = dxx.ReadWAV(bytes.NewReader(b)); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if f.Channels != 1 {
+			return nil, fmt.Errorf("%s: %w: %d channels", name, errMultichannel, f.Channels)
+		}
+		s.fs = f.SampleRate
+		return s, nil
+	case formatCSV:
+		if s.data, err = readCSV(bytes.NewReader(b)); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return s, nil
+	}
+
+	dt, _ := dxx.StringToDataType(s.format)
+	s.data, err = dxx.ReadWithOptions(bytes.NewReader(b), dt, -1, &dxx.Options{Scaler: &conv.Scaler{}, ByteOrder: s.order})
+	if err != nil {
+		var pe *dxx.ParseError
+		if errors.As(err, &pe) {
+			pe.File = name
+			return nil, pe
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if name != "-" {
+		if err := s.readMeta(name); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// readCompressed reads the compressed DXX file name. typ must be empty or the format of the extension.
+func readCompressed(name, typ string) (*signal, error) {
+	format := formatOf(name)
+	if _, err := dxx.StringToDataType(format); err != nil {
+		return nil, fmt.Errorf("%s: only DXX files can be compressed", name)
+	}
+	if typ != "" && !strings.EqualFold(typ, format) {
+		return nil, fmt.Errorf("%s: the format of a compressed file is given by its extension", name)
+	}
+	s := &signal{format: format, source: "extension", order: binary.LittleEndian}
+	var err error
+	if s.data, err = dxx.ReadFromFileWithOptions(name, &dxx.Options{Scaler: &conv.Scaler{}}); err != nil {
+		return nil, err
+	}
+	if err := s.readMeta(name); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readMeta reads the metadata sidecar of the DXX file name into s.
+// Interleaved multichannel data, whose sidecar records more than one channel, is rejected.
+func (s *signal) readMeta(name string) error {
+	m, err := dxx.ReadMeta(name)
+	if err != nil || m == nil {
+		return err
+	}
+	if m.Channels > 1 {
+		return fmt.Errorf("%s: %w: %d interleaved channels", name, errMultichannel, m.Channels)
+	}
+	s.meta, s.fs = m, m.SamplingRate
+	return nil
+}
+
+// readCSV reads the first column of CSV data. A first row which is not a number is taken as a header.
+func readCSV(r io.Reader) ([]float64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var data []float64
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(rec[0], 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		data = append(data, v)
+	}
+}
+
+// outputFormat returns the format of the output name.
+// It is typ if not empty, else the extension, else the format of the input.
+func outputFormat(name, typ, input string) (string, error) {
+	if typ != "" {
+		return parseFormat(typ)
+	}
+	if f := formatOf(name); f != "" {
+		return f, nil
+	}
+	return input, nil
+}
+
+// levelFactor returns the factor to convert the level of samples from a format to another.
+// WAV samples are in [-1, 1), so the int16 types are converted as Q15. The other levels are kept.
+func levelFactor(from, to string) float64 {
+	isShort := func(f string) bool { return f == dxx.DSA.String() || f == dxx.DSB.String() }
+	switch {
+	case isShort(from) && to == formatWAV:
+		return 1.0 / conv.FullScaleInt16
+	case from == formatWAV && isShort(to):
+		return conv.FullScaleInt16
+	default:
+		return 1
+	}
+}
+
+// writeSignal writes data to the file name, or stdout if name is "-", in the format.
+// fs is the sampling rate of WAV output. meta, if not nil, is written as the sidecar of a DXX file.
+// Files are written atomically.
+func writeSignal(name, format string, data []float64, f *ioFlags, fs int, meta *dxx.Meta) error {
+	if name == "-" {
+		return encodeSignal(stdout, format, data, f, fs)
+	}
+	if c, _ := dxx.CompressionOf(name); c != dxx.NoCompression {
+		if err := writeCompressed(name, format, data, f); err != nil {
+			return err
+		}
+		if meta == nil {
+			return nil
+		}
+		return dxx.WriteMeta(name, meta)
+	}
+	file, err := dxx.CreateAtomic(name, nil)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+	if err := encodeSignal(file, format, data, f, fs); err != nil {
+		return err
+	}
+	if err := file.Commit(); err != nil {
+		return err
+	}
+	if meta == nil || format == formatWAV || format == formatCSV {
+		return nil
+	}
+	return dxx.WriteMeta(name, meta)
+}
+
+// writeCompressed writes data to the compressed DXX file name, which must have the extension of the format.
+func writeCompressed(name, format string, data []float64, f *ioFlags) error {
+	if _, err := dxx.StringToDataType(format); err != nil || formatOf(name) != format {
+		return fmt.Errorf("%s: compressed output must be a DXX file with the extension of its format, %s", name, format)
+	}
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+	return dxx.WriteToFileWithOptions(name, data, opts)
+}
+
+// encodeSignal writes data to w in the format.
+func encodeSignal(w io.Writer, format string, data []float64, f *ioFlags, fs int) error {
+	switch format {
+	case formatWAV:
+		wf := dxx.WAVFormat{SampleRate: fs, Channels: 1, BitsPerSample: f.bits, Float: !f.pcm}
+		return dxx.WriteWAV(w, wf, data)
+	case formatCSV:
+		return writeASCII(w, data)
+	}
+	dt, err := dxx.StringToDataType(format)
+	if err != nil {
+		return err
+	}
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+	return dxx.WriteWithOptions(w, dt, data, opts)
+}
+
+// convertSignal writes s to the file name, converting its level to the output format.
+func convertSignal(name string, s *signal, data []float64, f *ioFlags) error {
+	format, err := outputFormat(name, f.outType, s.format)
+	if err != nil {
+		return err
+	}
+	if k := levelFactor(s.format, format); k != 1 {
+		data = scale(data, k)
+	}
+	meta := s.meta
+	if meta != nil && f.fs > 0 {
+		m := *meta
+		m.SamplingRate = f.fs
+		meta = &m
+	}
+	return writeSignal(name, format, data, f, f.samplingRate(s), meta)
+}
+
+// scale returns the samples multiplied by k.
+func scale(data []float64, k float64) []float64 {
+	ret := make([]float64, len(data))
+	for i, v := range data {
+		ret[i] = v * k
+	}
+	return ret
+}
+
+// writeASCII writes the samples one per line in the shortest form which reads back to the same value.
+func writeASCII(w io.Writer, data []float64) error {
+	bw := bufio.NewWriter(w)
+	var b []byte
+	for _, v := range data {
+		b = strconv.AppendFloat(b[:0], v, 'g', -1, 64)
+		b = append(b, '\n')
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+func main() {
+	log.SetFlags(0)
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	fs.Usage = func() {
+		log.Printf("Usage: %s [-from auto|big|little] [-n] src_dir dst_dir\n", fs.Name())
+		log.Printf("re-encodes the binary .DXX files (DSB, DFB, DDB) under src_dir to little-endian into dst_dir.\n")
+		log.Printf("metadata sidecars are copied along. dst_dir may be src_dir to convert in place.\n")
+		fs.PrintDefaults()
+	}
+	if err := run(fs, os.Args[1:], os.Stdout); err != nil {
+		log.Printf("error: %+v\n\n", err)
+		fs.Usage()
+		os.Exit(1)
+	}
+}
+
+// run converts the files as specified by the arguments, and prints the byte order of each file to stdout.
+func run(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	from := fs.String("from", "auto", "byte order of the source files: auto, big or little")
+	dryRun := fs.Bool("n", false, "print the detected byte orders without writing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("invalid arguments")
+	}
+	srcDir := fs.Arg(0)
+	dstDir := fs.Arg(1)
+
+	c := converter{dryRun: *dryRun, stdout: stdout}
+	switch *from {
+	case "auto":
+	case "big":
+		c.order = binary.BigEndian
+	case "little":
+		c.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("invalid byte order: %s", *from)
+	}
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		dt, err := dxx.StringToDataType(strings.TrimPrefix(filepath.Ext(path), "."))
+		if err != nil || !dt.IsBinary() {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		return c.convert(path, filepath.Join(dstDir, rel), dt)
+	})
+}
+
+// converter re-encodes binary .DXX files to little-endian.
+type converter struct {
+	// order is the byte order of the sources, or nil to detect it from the content of each file.
+	order  binary.ByteOrder
+	dryRun bool
+	stdout io.Writer
+}
+
+// convert re-encodes the src file to little-endian dst.
+func (c *converter) convert(src, dst string, dt dxx.DataType) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	order := c.order
+	if order == nil {
+		if order, err = dxx.DetectByteOrder(b, dt); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.stdout, "%s: %v\n", src, order)
+	if c.dryRun {
+		return nil
+	}
+
+	// the samples are re-encoded as stored.
+	data, err := dxx.ReadWithOptions(bytes.NewReader(b), dt, -1, &dxx.Options{Scaler: &conv.Scaler{}, ByteOrder: order})
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := dxx.WriteToFileWithOptions(dst, data, &dxx.Options{Scaler: &conv.Scaler{}}); err != nil {
+		return err
+	}
+
+	// copy the metadata sidecar if any.
+	if src == dst {
+		return nil
+	}
+	meta, err := dxx.ReadMeta(src)
+	if err != nil || meta == nil {
+		return err
+	}
+	return dxx.WriteMeta(dst, meta)
+}
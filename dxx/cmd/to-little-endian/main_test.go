@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// testRun runs the command with the arguments and returns its output.
+func testRun(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("to-little-endian", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var out bytes.Buffer
+	err := run(fs, args, &out)
+	return out.String(), err
+}
+
+// copyFixture copies the fixture of the dxx package to name under dir.
+func copyFixture(t *testing.T, fixture, dir, name string) {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name = filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readStored reads the stored values of the fixture of the dxx package in the byte order.
+func readStored(t *testing.T, name string, order binary.ByteOrder) []float64 {
+	t.Helper()
+	data, err := dxx.ReadFromFileWithOptions(name, &dxx.Options{Scaler: &conv.Scaler{}, ByteOrder: order})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func assertSame(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d samples, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s: sample %d is %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	src := t.TempDir()
+	copyFixture(t, "ir_be.DSB", src, "a.DSB")
+	copyFixture(t, "ir_le.DFB", src, "sub/b.DFB")
+	copyFixture(t, "ir_be.DDB", src, "sub/c.DDB")
+	copyFixture(t, "ir.DSA", src, "d.DSA")
+	meta := &dxx.Meta{SamplingRate: 44100, Creator: "test"}
+	if err := dxx.WriteMeta(filepath.Join(src, "sub", "c.DDB"), meta); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]float64{
+		"a.DSB":     readStored(t, filepath.Join(src, "a.DSB"), binary.BigEndian),
+		"sub/b.DFB": readStored(t, filepath.Join(src, "sub", "b.DFB"), binary.LittleEndian),
+		"sub/c.DDB": readStored(t, filepath.Join(src, "sub", "c.DDB"), binary.BigEndian),
+	}
+
+	// a dry run writes nothing
+	dst := filepath.Join(t.TempDir(), "dst")
+	out, err := testRun(t, "-n", src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		filepath.Join(src, "a.DSB") + ": BigEndian",
+		filepath.Join(src, "sub", "b.DFB") + ": LittleEndian",
+		filepath.Join(src, "sub", "c.DDB") + ": BigEndian",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("the output of -n has no %q:\n%s", line, out)
+		}
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("-n created %s", dst)
+	}
+
+	if _, err := testRun(t, src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range want {
+		assertSame(t, name, readStored(t, filepath.Join(dst, filepath.FromSlash(name)), binary.LittleEndian), values)
+	}
+	if m, err := dxx.ReadMeta(filepath.Join(dst, "sub", "c.DDB")); err != nil || m == nil || m.SamplingRate != 44100 || m.Creator != "test" {
+		t.Errorf("the sidecar of sub/c.DDB: got %+v, %v", m, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "d.DSA")); !os.IsNotExist(err) {
+		t.Errorf("ASCII d.DSA was converted: %v", err)
+	}
+
+	// in place, with the byte order given
+	in := t.TempDir()
+	copyFixture(t, "ir_be.DDB", in, "c.DDB")
+	if _, err := testRun(t, "-from", "big", in, in); err != nil {
+		t.Fatal(err)
+	}
+	assertSame(t, "c.DDB in place", readStored(t, filepath.Join(in, "c.DDB"), binary.LittleEndian), want["sub/c.DDB"])
+}
+
+func TestRunInvalidArguments(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{dir},
+		{dir, dir, dir},
+		{"-from", "middle", dir, dir},
+		{"-unknown", dir, dir},
+	} {
+		if _, err := testRun(t, args...); err == nil {
+			t.Errorf("run(%q) succeeded", args)
+		}
+	}
+}
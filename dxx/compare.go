@@ -0,0 +1,180 @@
+package dxx
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+// DefaultMaxLag is the largest lag searched by CompareWithOptions when CompareOptions.MaxLag is 0.
+const DefaultMaxLag = 1024
+
+// CompareOptions are the options of CompareWithOptions.
+type CompareOptions struct {
+	// Tolerance is the largest absolute difference at which samples are regarded as equal.
+	Tolerance float64
+	// Align aligns b to a by cross-correlation before comparing them.
+	Align bool
+	// MaxLag is the largest lag searched by Align, in samples. The default is DefaultMaxLag.
+	MaxLag int
+}
+
+// Diff is the difference between two signals a and b.
+// The errors are b - a, and a is the reference of SNR.
+type Diff struct {
+	// LenA and LenB are the lengths of the signals.
+	LenA, LenB int
+	// Lag is the delay of b relative to a in samples: a[i] is compared to b[i+Lag].
+	// It is 0 unless the signals are aligned.
+	Lag int
+	// Compared is the number of samples compared, where a and b overlap.
+	Compared int
+	// MaxAbsErr is the maximum absolute error and MaxAbsErrIndex is its index in a.
+	// MaxAbsErrIndex is -1 if no samples are compared.
+	MaxAbsErr      float64
+	MaxAbsErrIndex int
+	// RMSErr is the root mean square error.
+	RMSErr float64
+	// SNR is the signal to noise ratio [dB] of a to the error. It is +Inf if there is no error.
+	SNR float64
+	// FirstDiff is the index in a of the first sample which differs by more than the tolerance.
+	// It is -1 if every compared sample is within the tolerance.
+	FirstDiff int
+}
+
+// Equal reports whether the signals have the same length and no compared samples differ by more than the tolerance.
+// If the signals are aligned, the samples shifted out of the overlap are not compared.
+func (d Diff) Equal() bool {
+	return d.LenA == d.LenB && d.FirstDiff < 0
+}
+
+// Compare compares the signal b with the reference a sample by sample.
+// NaNs at the same index are regarded as equal, while a NaN against a number is an infinite error.
+func Compare(a, b []float64) Diff {
+	return CompareWithOptions(a, b, nil)
+}
+
+// CompareWithOptions compares the signal b with the reference a as specified by opts.
+func CompareWithOptions(a, b []float64, opts *CompareOptions) Diff {
+	if opts == nil {
+		opts = &CompareOptions{}
+	}
+	d := Diff{LenA: len(a), LenB: len(b), MaxAbsErrIndex: -1, FirstDiff: -1}
+	if opts.Align {
+		maxLag := opts.MaxLag
+		if maxLag <= 0 {
+			maxLag = DefaultMaxLag
+		}
+		d.Lag = bestLag(a, b, maxLag)
+	}
+
+	start, end := overlap(len(a), len(b), d.Lag)
+	var signal, noise float64
+	for i := start; i < end; i++ {
+		x, y := a[i], b[i+d.Lag]
+		e := math.Abs(y - x)
+		switch {
+		case x == y || (math.IsNaN(x) && math.IsNaN(y)):
+			e = 0
+		case math.IsNaN(x) || math.IsNaN(y):
+			e = math.Inf(1)
+		}
+		if !math.IsNaN(x) {
+			signal += x * x
+		}
+		noise += e * e
+		if d.MaxAbsErrIndex < 0 || e > d.MaxAbsErr {
+			d.MaxAbsErr, d.MaxAbsErrIndex = e, i
+		}
+		if d.FirstDiff < 0 && e > opts.Tolerance {
+			d.FirstDiff = i
+		}
+		d.Compared++
+	}
+	if d.Compared > 0 {
+		d.RMSErr = math.Sqrt(noise / float64(d.Compared))
+	}
+	d.SNR = 10 * math.Log10(signal/noise)
+	if noise == 0 {
+		d.SNR = math.Inf(1)
+	}
+	return d
+}
+
+// overlap returns the range [start, end) of i where a[i] overlaps b[i+lag].
+func overlap(lenA, lenB, lag int) (start, end int) {
+	start, end = 0, lenA
+	if lag < 0 {
+		start = -lag
+	}
+	if lenB-lag < end {
+		end = lenB - lag
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// bestLag returns the lag in [-maxLag, maxLag] which maximizes the cross-correlation of a and b.
+// b[i+lag] is correlated with a[i]. Among equal maxima, the lag nearest to 0 is chosen.
+// The cross-correlation is computed by FFT, and samples which are not finite are taken as 0.
+func bestLag(a, b []float64, maxLag int) int {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	// the lags of an empty overlap have the correlation 0.
+	n := 1
+	for n < len(a)+len(b)-1 {
+		n *= 2
+	}
+	fa, fb := make([]float64, n), make([]float64, n)
+	var ea, eb float64
+	for i, v := range a {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			fa[i] = v
+			ea += v * v
+		}
+	}
+	for i, v := range b {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			fb[i] = v
+			eb += v * v
+		}
+	}
+	// c[lag mod n] = Σ a[i] b[i+lag]
+	sa, sb := fft.FFTReal(fa), fft.FFTReal(fb)
+	for k := range sa {
+		sa[k] = cmplx.Conj(sa[k]) * sb[k]
+	}
+	c := fft.IFFT(sa)
+	corr := func(lag int) float64 {
+		if lag <= -len(a) || lag >= len(b) {
+			return 0
+		}
+		return real(c[(lag+n)%n])
+	}
+
+	// correlations which differ by the rounding error of the FFT are equal.
+	tol := 1e-9 * math.Sqrt(ea*eb)
+	var (
+		best    int
+		bestSum = math.Inf(-1)
+	)
+	for k := 0; k <= maxLag; k++ {
+		for _, lag := range []int{k, -k} {
+			if k == 0 && lag < 0 {
+				continue
+			}
+			if sum := corr(lag); sum > bestSum+tol {
+				best, bestSum = lag, sum
+			}
+		}
+		if k >= len(a) && k >= len(b) {
+			// the remaining lags have empty overlaps
+			break
+		}
+	}
+	return best
+}
@@ -0,0 +1,138 @@
+package dxx
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCompareRecoversLagAndGain(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	a := make([]float64, 48000)
+	for i := range a {
+		a[i] = rng.NormFloat64()
+	}
+	for _, lag := range []int{0, 1, -1, 37, -250, 999, -1000} {
+		for _, gain := range []float64{1, 0.5, 1.25, 2} {
+			// b[i+lag] = gain * a[i]
+			b := make([]float64, len(a))
+			for i := range b {
+				if j := i - lag; j >= 0 && j < len(a) {
+					b[i] = gain * a[j]
+				}
+			}
+			d := CompareWithOptions(a, b, &CompareOptions{Align: true, MaxLag: 1000})
+			if d.Lag != lag {
+				t.Errorf("lag %d, gain %v: Lag = %d", lag, gain, d.Lag)
+				continue
+			}
+			if want := len(a) - abs(lag); d.Compared != want {
+				t.Errorf("lag %d, gain %v: Compared = %d, want %d", lag, gain, d.Compared, want)
+			}
+			// the error is (gain - 1) a, so the SNR is -20 log10 |gain - 1|
+			if want := -20 * math.Log10(math.Abs(gain-1)); math.Abs(d.SNR-want) > 1e-9 {
+				t.Errorf("lag %d, gain %v: SNR = %v dB, want %v dB", lag, gain, d.SNR, want)
+			}
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// directBestLag is bestLag computed in the time domain, as it was before the FFT.
+func directBestLag(a, b []float64, maxLag int) int {
+	var (
+		best    int
+		bestSum = math.Inf(-1)
+	)
+	for k := 0; k <= maxLag; k++ {
+		for _, lag := range []int{k, -k} {
+			if k == 0 && lag < 0 {
+				continue
+			}
+			start, end := overlap(len(a), len(b), lag)
+			var sum float64
+			for i := start; i < end; i++ {
+				if x, y := a[i], b[i+lag]; finite(x) && finite(y) {
+					sum += x * y
+				}
+			}
+			if sum > bestSum+1e-9 {
+				best, bestSum = lag, sum
+			}
+		}
+	}
+	return best
+}
+
+func finite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+func TestBestLag(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	signal := func(n int) []float64 {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = rng.NormFloat64()
+		}
+		return x
+	}
+	for _, c := range []struct {
+		lenA, lenB, maxLag int
+	}{
+		{1, 1, 0}, {1, 5, 10}, {5, 1, 10}, {100, 100, 20}, {100, 37, 200}, {37, 100, 200}, {1000, 999, 1000},
+	} {
+		for trial := 0; trial < 5; trial++ {
+			a, b := signal(c.lenA), signal(c.lenB)
+			if got, want := bestLag(a, b, c.maxLag), directBestLag(a, b, c.maxLag); got != want {
+				t.Errorf("bestLag(%d, %d, %d) = %d, want %d", c.lenA, c.lenB, c.maxLag, got, want)
+			}
+		}
+	}
+
+	// equal maxima: the lag nearest to 0
+	periodic := make([]float64, 64)
+	for i := range periodic {
+		periodic[i] = float64(i%8) - 3.5
+	}
+	if got := bestLag(periodic, periodic, 20); got != 0 {
+		t.Errorf("bestLag of a periodic signal = %d, want 0", got)
+	}
+	// negative correlations at every overlapping lag: an empty overlap has the correlation 0
+	if got, want := bestLag([]float64{1}, []float64{-1}, 3), directBestLag([]float64{1}, []float64{-1}, 3); got != want {
+		t.Errorf("bestLag of opposite samples = %d, want %d", got, want)
+	}
+	// NaN and Inf samples are ignored
+	a := signal(200)
+	b := make([]float64, 200)
+	copy(b[5:], a)
+	a[10], a[20], b[100] = math.NaN(), math.Inf(1), math.Inf(-1)
+	if got := bestLag(a, b, 50); got != 5 {
+		t.Errorf("bestLag with NaN and Inf = %d, want 5", got)
+	}
+	if got := bestLag(nil, b, 50); got != 0 {
+		t.Errorf("bestLag of an empty signal = %d, want 0", got)
+	}
+}
+
+// BenchmarkCompareAlign aligns 10 seconds at 48 kHz with a lag of up to 1 second.
+func BenchmarkCompareAlign(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	x := make([]float64, 480000)
+	for i := range x {
+		x[i] = rng.NormFloat64()
+	}
+	y := append(make([]float64, 12345), x...)
+	opts := &CompareOptions{Align: true, MaxLag: 48000}
+	for i := 0; i < b.N; i++ {
+		if d := CompareWithOptions(x, y, opts); d.Lag != 12345 {
+			b.Fatalf("Lag = %d, want 12345", d.Lag)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package dxx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrUnknownPredictor = errors.New("unknown predictor")
+)
+
+// Compression is the compression of a DXX file, given by the outer extension of the file name.
+// e.g. sound.DDB.gz is DDB data compressed with gzip.
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	// Gzip is gzip compression, with the extension .gz.
+	Gzip
+	// Zlib is zlib compression, with the extension .zlib.
+	Zlib
+	// Flate is raw DEFLATE compression, with the extension .flate.
+	Flate
+)
+
+// String returns the compression name as string.
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Zlib:
+		return "zlib"
+	case Flate:
+		return "flate"
+	default:
+		return "unknown compression"
+	}
+}
+
+// Ext returns the extension of the compression including the dot, or "" for NoCompression.
+func (c Compression) Ext() string {
+	switch c {
+	case Gzip:
+		return ".gz"
+	case Zlib:
+		return ".zlib"
+	case Flate:
+		return ".flate"
+	default:
+		return ""
+	}
+}
+
+// CompressionOf returns the compression of the file and the file name without the compression extension.
+// The extension is matched case-insensitively.
+func CompressionOf(filename string) (Compression, string) {
+	e := filepath.Ext(filename)
+	for _, c := range []Compression{Gzip, Zlib, Flate} {
+		if strings.EqualFold(e, c.Ext()) {
+			return c, strings.TrimSuffix(filename, e)
+		}
+	}
+	return NoCompression, filename
+}
+
+// Predictor is a lossless transform of binary samples which makes them compress better.
+// It stores the difference between successive samples instead of the samples,
+// which is mostly zero bits for smooth signals such as impulse responses.
+// The differences are stored byte plane by byte plane in blocks of 4096 samples,
+// so that the compressor sees runs of similar bytes.
+// Predictors are applied only to binary data in compressed files, which record the predictor they were written with:
+// gzip files in the header comment, and zlib and flate files in a line "dxx-predictor=<name>" before the data.
+// Big-endian data records its byte order after the name, as in "dxx-predictor=delta;big-endian",
+// since the differences of Delta depend on it. Data without a record has no predictor,
+// and predicted data without a byte order is little-endian.
+type Predictor int
+
+const (
+	NoPredictor Predictor = iota
+	// Delta stores the difference of the bit patterns of successive samples as unsigned integers.
+	Delta
+	// XOR stores the XOR of the bit patterns of successive samples.
+	XOR
+)
+
+// String returns the predictor name as string.
+func (p Predictor) String() string {
+	switch p {
+	case NoPredictor:
+		return "none"
+	case Delta:
+		return "delta"
+	case XOR:
+		return "xor"
+	default:
+		return "unknown predictor"
+	}
+}
+
+// predictorComment is the prefix of the gzip header comment, or of the first line of zlib and flate data,
+// which records the predictor.
+const predictorComment = "dxx-predictor="
+
+// bigEndianSuffix follows the predictor name in the record of big-endian data.
+const bigEndianSuffix = ";big-endian"
+
+// formatPredictor returns the record of the predictor of binary data in the byte order.
+func formatPredictor(pred Predictor, order binary.ByteOrder) string {
+	if order == binary.BigEndian {
+		return predictorComment + pred.String() + bigEndianSuffix
+	}
+	return predictorComment + pred.String()
+}
+
+// StringToPredictor determines predictor from specified string, case-insensitively.
+// If the specified string is invalid, this func returns error.
+func StringToPredictor(s string) (Predictor, error) {
+	for _, p := range []Predictor{NoPredictor, Delta, XOR} {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return NoPredictor, fmt.Errorf("%w: %q", ErrUnknownPredictor, s)
+}
+
+// parsePredictor parses the predictor recorded in a gzip header comment, and the byte order of the data.
+// The byte order is nil if none is recorded.
+func parsePredictor(comment string) (Predictor, binary.ByteOrder, error) {
+	if !strings.HasPrefix(comment, predictorComment) {
+		return NoPredictor, nil, nil
+	}
+	name := strings.TrimPrefix(comment, predictorComment)
+	var order binary.ByteOrder
+	if strings.HasSuffix(name, bigEndianSuffix) {
+		name, order = strings.TrimSuffix(name, bigEndianSuffix), binary.BigEndian
+	}
+	pred, err := StringToPredictor(name)
+	return pred, order, err
+}
+
+// readPredictorLine reads the line which records the predictor at the start of zlib and flate data.
+// If the data does not start with the line, nothing is read and NoPredictor is returned.
+func readPredictorLine(r *bufio.Reader) (Predictor, binary.ByteOrder, error) {
+	if b, err := r.Peek(len(predictorComment)); err != nil || string(b) != predictorComment {
+		// errors are returned again by the next read.
+		return NoPredictor, nil, nil
+	}
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return NoPredictor, nil, fmt.Errorf("%w: no end of the line %q", ErrUnknownPredictor, line)
+	}
+	return parsePredictor(strings.TrimSuffix(string(line), "\n"))
+}
+
+// writePredictorLine records the predictor and the byte order at the start of zlib and flate data, which have no header for them.
+// Nothing is written for NoPredictor, so that the data decompresses to plain DXX data.
+func writePredictorLine(w io.Writer, pred Predictor, order binary.ByteOrder) error {
+	if pred == NoPredictor {
+		return nil
+	}
+	_, err := io.WriteString(w, formatPredictor(pred, order)+"\n")
+	return err
+}
+
+// dataFile is a file opened for reading, decompressed if the file is compressed.
+type dataFile struct {
+	io.Reader
+	f  fs.File
+	zr io.Closer
+	// size is the size of the data, or -1 if the file is compressed.
+	size int64
+	// pred is the predictor of compressed data, and order is the byte order recorded with it, or nil.
+	pred  Predictor
+	order binary.ByteOrder
+}
+
+// openData opens the file name in fsys.
+// The predictor of compressed data is taken from the record in the data. See Predictor.
+func openData(fsys fs.FS, name string) (*dataFile, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	d := &dataFile{Reader: f, f: f, size: -1}
+	c, _ := CompressionOf(name)
+	switch c {
+	case NoCompression:
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		d.size = info.Size()
+	case Gzip:
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if d.pred, d.order, err = parsePredictor(zr.Comment); err != nil {
+			f.Close()
+			return nil, err
+		}
+		d.Reader, d.zr = zr, zr
+	case Zlib, Flate:
+		var zr io.ReadCloser
+		if c == Zlib {
+			if zr, err = zlib.NewReader(f); err != nil {
+				f.Close()
+				return nil, err
+			}
+		} else {
+			zr = flate.NewReader(f)
+		}
+		br := bufio.NewReader(zr)
+		if d.pred, d.order, err = readPredictorLine(br); err != nil {
+			zr.Close()
+			f.Close()
+			return nil, err
+		}
+		d.Reader, d.zr = br, zr
+	}
+	return d, nil
+}
+
+// Close closes the decompressor and the file.
+func (d *dataFile) Close() error {
+	var err error
+	if d.zr != nil {
+		err = d.zr.Close()
+	}
+	if cerr := d.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// compressWriter returns a writer which compresses the data written to w.
+// The predictor and the byte order of the data are recorded in the gzip header, or written before the data of zlib and flate.
+func compressWriter(w io.Writer, c Compression, pred Predictor, order binary.ByteOrder) (io.WriteCloser, error) {
+	switch c {
+	case Gzip:
+		zw := gzip.NewWriter(w)
+		if pred != NoPredictor {
+			zw.Comment = formatPredictor(pred, order)
+		}
+		return zw, nil
+	case Zlib:
+		zw := zlib.NewWriter(w)
+		return zw, writePredictorLine(zw, pred, order)
+	case Flate:
+		zw, err := flate.NewWriter(w, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		return zw, writePredictorLine(zw, pred, order)
+	default:
+		return nil, fmt.Errorf("unknown compression: %d", c)
+	}
+}
+
+// word reads and writes the bit pattern of a sample of byte length bl.
+type word struct {
+	bl    int
+	order binary.ByteOrder
+}
+
+func (w word) get(b []byte) uint64 {
+	switch w.bl {
+	case 2:
+		return uint64(w.order.Uint16(b))
+	case 4:
+		return uint64(w.order.Uint32(b))
+	default:
+		return w.order.Uint64(b)
+	}
+}
+
+func (w word) put(b []byte, v uint64) {
+	switch w.bl {
+	case 2:
+		w.order.PutUint16(b, uint16(v))
+	case 4:
+		w.order.PutUint32(b, uint32(v))
+	default:
+		w.order.PutUint64(b, v)
+	}
+}
+
+// predict transforms the whole samples in b by the predictor and stores them byte plane by byte plane:
+// the lowest bytes of all the samples first, and the highest bytes last. out must be as long as b.
+// prev is the bit pattern of the sample before b, and the last sample of b is returned.
+func (w word) predict(out, b []byte, pred Predictor, prev uint64) uint64 {
+	n := len(b) / w.bl
+	var tmp [8]byte
+	for i := 0; i < n; i++ {
+		v := w.get(b[i*w.bl:])
+		if pred == XOR {
+			w.put(tmp[:], v^prev)
+		} else {
+			w.put(tmp[:], v-prev)
+		}
+		prev = v
+		for k := 0; k < w.bl; k++ {
+			out[k*n+i] = tmp[k]
+		}
+	}
+	return prev
+}
+
+// unpredict inverts predict.
+func (w word) unpredict(out, b []byte, pred Predictor, prev uint64) uint64 {
+	n := len(b) / w.bl
+	var tmp [8]byte
+	for i := 0; i < n; i++ {
+		for k := 0; k < w.bl; k++ {
+			tmp[k] = b[k*n+i]
+		}
+		v := w.get(tmp[:])
+		if pred == XOR {
+			v ^= prev
+		} else {
+			v += prev
+		}
+		w.put(out[i*w.bl:], v)
+		prev = v
+	}
+	return prev
+}
+
+// predictWriter applies the predictor to the binary samples written to w in blocks of chunkSamples samples.
+// Close must be called to write the last block.
+type predictWriter struct {
+	w     io.Writer
+	word  word
+	pred  Predictor
+	prev  uint64
+	block []byte
+	out   []byte
+}
+
+func newPredictWriter(w io.Writer, dt DataType, order binary.ByteOrder, pred Predictor) *predictWriter {
+	size := chunkSamples * dt.ByteLen()
+	return &predictWriter{
+		w:     w,
+		word:  word{dt.ByteLen(), order},
+		pred:  pred,
+		block: make([]byte, 0, size),
+		out:   make([]byte, size),
+	}
+}
+
+func (w *predictWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		k := copy(w.block[len(w.block):cap(w.block)], p)
+		w.block = w.block[:len(w.block)+k]
+		p = p[k:]
+		n += k
+		if len(w.block) == cap(w.block) {
+			if err := w.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *predictWriter) flush() error {
+	w.prev = w.word.predict(w.out, w.block, w.pred, w.prev)
+	_, err := w.w.Write(w.out[:len(w.block)])
+	w.block = w.block[:0]
+	return err
+}
+
+// Close writes the last block. It does not close w.
+func (w *predictWriter) Close() error {
+	if len(w.block)%w.word.bl != 0 {
+		return ErrTruncated
+	}
+	return w.flush()
+}
+
+// unpredictReader inverts the predictor of the binary samples read from r.
+type unpredictReader struct {
+	r    io.Reader
+	word word
+	// swap reports whether the samples are returned in the other byte order than they are stored.
+	swap  bool
+	pred  Predictor
+	prev  uint64
+	block []byte
+	buf   []byte
+	out   []byte
+	err   error
+}
+
+// newUnpredictReader returns a reader of the samples of r, which are stored in the byte order,
+// in the byte order out.
+func newUnpredictReader(r io.Reader, dt DataType, order, out binary.ByteOrder, pred Predictor) *unpredictReader {
+	size := chunkSamples * dt.ByteLen()
+	return &unpredictReader{
+		r:     r,
+		word:  word{dt.ByteLen(), order},
+		swap:  order != out,
+		pred:  pred,
+		block: make([]byte, size),
+		buf:   make([]byte, size),
+	}
+}
+
+func (r *unpredictReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := io.ReadFull(r.r, r.block)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		r.err = err
+		m := n - n%r.word.bl
+		r.prev = r.word.unpredict(r.buf, r.block[:m], r.pred, r.prev)
+		if r.swap {
+			swapBytes(r.buf[:m], r.word.bl)
+		}
+		// the bytes of an incomplete sample are passed through for the reader to report ErrTruncated.
+		copy(r.buf[m:], r.block[m:n])
+		r.out = r.buf[:n]
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// swapBytes reverses the bytes of each sample of byte length bl in b.
+func swapBytes(b []byte, bl int) {
+	for i := 0; i+bl <= len(b); i += bl {
+		for j, k := i, i+bl-1; j < k; j, k = j+1, k-1 {
+			b[j], b[k] = b[k], b[j]
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package dxx
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+// predictorSignal is a decaying sine longer than a block of the predictors, with a sample at each extreme.
+func predictorSignal(dt DataType) []float64 {
+	data := make([]float64, chunkSamples*2+123)
+	for i := range data {
+		// +0 instead of -0, which DSB cannot store
+		data[i] = math.Round(30000*math.Exp(-float64(i)/3000)*math.Sin(float64(i)/7)) + 0
+	}
+	if dt == DSB {
+		data[10], data[11] = math.MaxInt16, math.MinInt16
+	} else {
+		data[10], data[11] = math.Inf(1), math.SmallestNonzeroFloat64
+	}
+	return data
+}
+
+func TestPredictorRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []Compression{Gzip, Zlib, Flate} {
+		for _, pred := range []Predictor{NoPredictor, Delta, XOR} {
+			for _, dt := range []DataType{DSB, DFB, DDB} {
+				for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+					name := filepath.Join(dir, "x_"+pred.String()+"_"+order.String()+"."+dt.String()+c.Ext())
+					data := predictorSignal(dt)
+					opts := &Options{Scaler: &conv.Scaler{}, ByteOrder: order, Predictor: pred}
+					if err := WriteToFileWithOptions(name, data, opts); err != nil {
+						t.Fatal(err)
+					}
+					// the predictor is read from the file
+					got, err := ReadFromFileWithOptions(name, &Options{Scaler: &conv.Scaler{}, ByteOrder: order})
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if dt == DFB {
+						// float32 keeps the extremes except the smallest float64
+						data[11] = 0
+					}
+					assertSameFloat64s(t, got, data)
+
+					// Open reads little-endian data, and predicted data in any byte order, which records its byte order
+					if order == binary.LittleEndian || pred != NoPredictor {
+						f, err := Open(name)
+						if err != nil {
+							t.Fatal(err)
+						}
+						got, err := f.Slice(0, f.Len())
+						f.Close()
+						if err != nil {
+							t.Fatal(err)
+						}
+						assertSameFloat64s(t, got, data)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestPredictorLine checks that zlib and flate files without a predictor decompress to plain DXX data,
+// and that the predictor line is read back.
+func TestPredictorLine(t *testing.T) {
+	dir := t.TempDir()
+	data := predictorSignal(DSB)
+	var plain bytes.Buffer
+	if err := WriteWithOptions(&plain, DSB, data, &Options{Scaler: &conv.Scaler{}}); err != nil {
+		t.Fatal(err)
+	}
+	decompress := map[Compression]func(io.Reader) (io.Reader, error){
+		Zlib:  func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		Flate: func(r io.Reader) (io.Reader, error) { return flate.NewReader(r), nil },
+	}
+	for c, decompress := range decompress {
+		for _, pred := range []Predictor{NoPredictor, Delta} {
+			name := filepath.Join(dir, "x_"+pred.String()+".DSB"+c.Ext())
+			if err := WriteToFileWithOptions(name, data, &Options{Scaler: &conv.Scaler{}, Predictor: pred}); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zr, err := decompress(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(zr)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pred == NoPredictor {
+				if !bytes.Equal(b, plain.Bytes()) {
+					t.Errorf("%s: does not decompress to the DSB data", c)
+				}
+				continue
+			}
+			if line := predictorComment + "delta\n"; !bytes.HasPrefix(b, []byte(line)) || len(b) != len(line)+plain.Len() {
+				t.Errorf("%s: decompresses to %d bytes starting with %q, want %q and %d bytes", c, len(b), b[:20], line, plain.Len())
+			}
+		}
+
+		name := filepath.Join(dir, "unknown.DSB"+c.Ext())
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw, err := compressWriter(f, c, NoPredictor, binary.LittleEndian)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(zw, predictorComment+"lpc\n\x00\x00")
+		zw.Close()
+		f.Close()
+		if _, err := ReadFromFile(name); !errors.Is(err, ErrUnknownPredictor) {
+			t.Errorf("%s: reading an unknown predictor: got %v, want ErrUnknownPredictor", c, err)
+		}
+	}
+}
+
+// TestPredictorBigEndian checks that predicted big-endian data is read by its recorded byte order.
+func TestPredictorBigEndian(t *testing.T) {
+	dir := t.TempDir()
+	preserve := &Options{Scaler: &conv.Scaler{}}
+	for _, c := range []Compression{Gzip, Zlib, Flate} {
+		for _, pred := range []Predictor{Delta, XOR} {
+			for _, dt := range []DataType{DSB, DFB, DDB} {
+				data := predictorSignal(dt)
+				if dt == DFB {
+					// float32 keeps the extremes except the smallest float64
+					data[11] = 0
+				}
+				name := filepath.Join(dir, "x_"+pred.String()+"."+dt.String()+c.Ext())
+				if err := WriteToFileWithOptions(name, data, &Options{Scaler: &conv.Scaler{}, ByteOrder: binary.BigEndian, Predictor: pred}); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, order := range []binary.ByteOrder{nil, binary.LittleEndian, binary.BigEndian} {
+					got, err := ReadFromFileWithOptions(name, &Options{Scaler: &conv.Scaler{}, ByteOrder: order})
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					assertSameFloat64s(t, got, data)
+				}
+
+				f, err := Open(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := f.Slice(0, f.Len())
+				f.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertSameFloat64s(t, got, data)
+
+				// ReadFromFileAuto detects the byte order of the unpredicted data and normalises as ReadFromFile.
+				// The extremes are left out, which make a DDB signal look like DFB.
+				data[10], data[11] = 0, 0
+				if err := WriteToFileWithOptions(name, data, &Options{Scaler: &conv.Scaler{}, ByteOrder: binary.BigEndian, Predictor: pred}); err != nil {
+					t.Fatal(err)
+				}
+				plain := filepath.Join(dir, "plain_"+pred.String()+"."+dt.String())
+				if err := WriteToFileWithOptions(plain, data, preserve); err != nil {
+					t.Fatal(err)
+				}
+				want, err := ReadFromFile(plain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, d, err := ReadFromFileAuto(name)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if d.DataType != dt || d.ByteOrder != binary.BigEndian {
+					t.Errorf("%s: detected %v in %v, want %v in big-endian", name, d.DataType, d.ByteOrder, dt)
+				}
+				assertSameFloat64s(t, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package dxx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUndetectable = errors.New("cannot detect the data type")
+)
+
+// sniffLen is the number of leading bytes examined by Detect.
+const sniffLen = 64 << 10
+
+// plausibleScore is the score from which a binary data type is accepted
+// without considering the less preferred types.
+const plausibleScore = 0.99
+
+// maxPlausibleStep is the largest plausible difference between consecutive DSB samples.
+const maxPlausibleStep = 1 << 13
+
+// rejectMargin is how far below plausibleScore a type is rejected with certainty.
+const rejectMargin = 0.2
+
+// Detection is the result of the data type detection.
+type Detection struct {
+	DataType DataType
+	// ByteOrder is the byte order of binary data. It is nil for ASCII data.
+	ByteOrder binary.ByteOrder
+	// Confidence is how certain the detection is, from 0 (a guess) to 1 (certain).
+	Confidence float64
+	// FromExtension reports whether the data type was taken from the extension of the file.
+	FromExtension bool
+}
+
+func (d Detection) String() string {
+	src := "content"
+	if d.FromExtension {
+		src = "extension"
+	}
+	if d.ByteOrder == nil {
+		return fmt.Sprintf("%s (%s, confidence %.2f)", d.DataType, src, d.Confidence)
+	}
+	return fmt.Sprintf("%s %s (%s, confidence %.2f)", d.DataType, d.ByteOrder, src, d.Confidence)
+}
+
+// Detect guesses the data type of DXX data from its content.
+// It examines up to the first 64 KiB from the current offset of r and seeks back to it before returning.
+//
+// Data consisting of printable text is ASCII: DSA if every value is an integer in the range of int16,
+// DDA if some value needs the precision of float64, and DFA if the values look like float32 values
+// printed in the shortest form. Short decimals like 0.5 fit both DFA and DDA, and are reported as
+// DDA with a lower confidence.
+//
+// Otherwise the data is decoded as each binary type in both byte orders, and the types are scored
+// by how plausible the decoded values are as a signal. Only the types which the size of the data
+// is a multiple of are considered. DFB is preferred over DDB, and DDB over DSB, because floating-point
+// data decoded as a wider or narrower type rarely looks plausible, while the opposite is not true.
+// Data consisting of zeros only is reported with confidence 0.
+// If no type is plausible, Detect reports the most plausible one with a low confidence.
+func Detect(r io.ReadSeeker) (Detection, error) {
+	b, size, err := sniff(r)
+	if err != nil {
+		return Detection{}, err
+	}
+	return detect(b, size)
+}
+
+func detect(b []byte, size int64) (Detection, error) {
+	if len(b) == 0 {
+		return Detection{}, ErrUndetectable
+	}
+	if isText(b) {
+		if d, ok := detectASCII(b, int64(len(b)) < size); ok {
+			return d, nil
+		}
+	}
+	return detectBinary(b, size)
+}
+
+// sniff returns up to the first sniffLen bytes from the current offset of r and
+// the size of the data from there, and seeks back to the offset.
+func sniff(r io.ReadSeeker) ([]byte, int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, err
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, 0, err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return b[:n], end - start, nil
+}
+
+// ReadFromFileAuto reads the DXX file and reports the data type it was read as.
+// If the extension is a data type and the content agrees with it, the extension is used.
+// Otherwise, for a missing or wrong extension, the data type is detected from the content as Detect does.
+// The byte order of binary data is always detected from the content.
+// Compressed files are decompressed into memory before the detection.
+func ReadFromFileAuto(filename string) ([]float64, Detection, error) {
+	df, err := openData(osFS{}, filename)
+	if err != nil {
+		return nil, Detection{}, err
+	}
+	defer df.Close()
+	f, err := seekable(filename, df)
+	if err != nil {
+		return nil, Detection{}, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	b, size, err := sniff(f)
+	if err != nil {
+		return nil, Detection{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	d, ok := Detection{}, false
+	if dt, err := StringToDataType(ext(filename)); err == nil {
+		d, ok = checkExtension(b, size, dt)
+	}
+	if !ok {
+		if d, err = detect(b, size); err != nil {
+			return nil, Detection{}, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	length := -1
+	if d.DataType.IsBinary() {
+		length = int(size) / d.DataType.ByteLen()
+	}
+	data, err := ReadWithOptions(bufio.NewReader(f), d.DataType, length, &Options{ByteOrder: d.ByteOrder})
+	if err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = filename
+			return nil, d, pe
+		}
+		return nil, d, fmt.Errorf("%s: %w", filename, err)
+	}
+	return data, d, nil
+}
+
+// seekable returns the data of the file for Detect.
+// Compressed data is decompressed into memory, inverting the predictor of the data type of the extension.
+func seekable(filename string, df *dataFile) (io.ReadSeeker, error) {
+	if df.size >= 0 {
+		if rs, ok := df.f.(io.ReadSeeker); ok {
+			return rs, nil
+		}
+	}
+	var r io.Reader = df
+	if df.pred != NoPredictor {
+		dt, err := StringToDataType(ext(filename))
+		if err != nil || !dt.IsBinary() {
+			return nil, fmt.Errorf("data with predictor %s needs a binary data type extension", df.pred)
+		}
+		order := df.order
+		if order == nil {
+			order = binary.LittleEndian
+		}
+		// the samples are kept in their byte order for the detection.
+		r = newUnpredictReader(df, dt, order, order, df.pred)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// checkExtension reports whether the data can be of dt, which is given by the extension.
+// ASCII data must parse as dt. Binary data must not be text, its size must be a multiple of
+// the sample size, and dt must be as plausible as the type detected from the content.
+func checkExtension(b []byte, size int64, dt DataType) (Detection, bool) {
+	d := Detection{DataType: dt, Confidence: 1, FromExtension: true}
+	if !dt.IsBinary() {
+		if !isText(b) {
+			return Detection{}, false
+		}
+		sc := newASCIIScanner(bytes.NewReader(completeLines(b, int64(len(b)) < size)), dt)
+		for {
+			_, err := sc.next()
+			if err == io.EOF {
+				return d, true
+			}
+			if err != nil {
+				return Detection{}, false
+			}
+		}
+	}
+	if size%int64(dt.ByteLen()) != 0 || len(b) < dt.ByteLen() || isText(b) {
+		return Detection{}, false
+	}
+	var score float64
+	score, d.ByteOrder = scoreBinary(b, dt)
+	if cd, err := detectBinary(b, size); err == nil && cd.DataType != dt {
+		if cs, _ := scoreBinary(b, cd.DataType); cs > score {
+			return Detection{}, false
+		}
+	}
+	return d, true
+}
+
+// isText reports whether b consists of printable ASCII characters and whitespace.
+func isText(b []byte) bool {
+	for _, c := range b {
+		if (c < ' ' || c > '~') && c != '\t' && c != '\n' && c != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// completeLines drops the last line of b if it may continue after b.
+func completeLines(b []byte, more bool) []byte {
+	if more {
+		if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+			return b[:i+1]
+		}
+	}
+	return b
+}
+
+// detectASCII classifies the values of ASCII data.
+// It reports false if some line is not a number.
+func detectASCII(b []byte, more bool) (Detection, bool) {
+	var (
+		values    int
+		isFloat   bool
+		isDouble  bool
+		maxDigits int
+	)
+	for _, line := range strings.Split(string(completeLines(b, more)), "\n") {
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, CommentPrefix) {
+			continue
+		}
+		values++
+		if _, err := strconv.ParseInt(text, 10, 16); err == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Detection{}, false
+		}
+		isFloat = true
+		// a value printed from float32 reads back as the float32 value printed in the shortest form.
+		s := strconv.FormatFloat(float64(float32(v)), 'g', -1, 32)
+		if v32, _ := strconv.ParseFloat(s, 64); v32 != v && !math.IsNaN(v) {
+			isDouble = true
+		}
+		if d := significantDigits(s); d > maxDigits {
+			maxDigits = d
+		}
+	}
+
+	switch {
+	case values == 0:
+		return Detection{DataType: DDA}, true
+	case !isFloat:
+		return Detection{DataType: DSA, Confidence: 1}, true
+	case isDouble:
+		return Detection{DataType: DDA, Confidence: 1}, true
+	case maxDigits >= 7:
+		// arbitrary float32 values need 6 to 9 digits, while doubles computed at full precision need 15 to 17.
+		return Detection{DataType: DFA, Confidence: 0.75}, true
+	default:
+		return Detection{DataType: DDA, Confidence: 0.5}, true
+	}
+}
+
+// significantDigits returns the number of significant digits of the number formatted by strconv.FormatFloat.
+func significantDigits(s string) int {
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimLeft(s, "+-0.")
+	return len(strings.ReplaceAll(s, ".", ""))
+}
+
+// detectBinary scores the binary data types and chooses the most preferred plausible one.
+func detectBinary(b []byte, size int64) (Detection, error) {
+	var (
+		best    [3]Detection
+		score   [3]float64
+		aligned [3]bool
+	)
+	for i, dt := range []DataType{DFB, DDB, DSB} {
+		if size%int64(dt.ByteLen()) != 0 || len(b) < dt.ByteLen() {
+			continue
+		}
+		aligned[i] = true
+		best[i] = Detection{DataType: dt}
+		score[i], best[i].ByteOrder = scoreBinary(b, dt)
+	}
+
+	chosen := -1
+	for i := range best {
+		if aligned[i] && score[i] >= plausibleScore {
+			chosen = i
+			break
+		}
+	}
+	if chosen < 0 {
+		for i := range best {
+			if aligned[i] && (chosen < 0 || score[i] > score[chosen]) {
+				chosen = i
+			}
+		}
+	}
+	if chosen < 0 {
+		return Detection{}, ErrUndetectable
+	}
+
+	// the confidence is lowered by the more preferred types which are not clearly implausible.
+	d := best[chosen]
+	d.Confidence = score[chosen]
+	for i := 0; i < chosen; i++ {
+		if aligned[i] {
+			d.Confidence *= math.Min(math.Max((plausibleScore-score[i])/rejectMargin, 0), 1)
+		}
+	}
+	if isZero(b) {
+		d.Confidence = 0
+	}
+	return d, nil
+}
+
+// scoreBinary returns the plausibility of b as the binary data type and the byte order which gives it.
+// If both orders are equally plausible DSB, e.g. a quiet signal whose swapped bytes still make small steps,
+// the order of the smoother samples is chosen.
+func scoreBinary(b []byte, dt DataType) (float64, binary.ByteOrder) {
+	b = b[:len(b)-len(b)%dt.ByteLen()]
+	le := plausibility(b, dt, binary.LittleEndian)
+	be := plausibility(b, dt, binary.BigEndian)
+	if be > le || (be == le && dt == DSB && variation(b, binary.BigEndian) < variation(b, binary.LittleEndian)) {
+		return be, binary.BigEndian
+	}
+	return le, binary.LittleEndian
+}
+
+// variation returns the sum of the absolute differences between the consecutive DSB samples of b.
+func variation(b []byte, order binary.ByteOrder) float64 {
+	var sum, prev float64
+	for i := 0; i+ByteLenShort <= len(b); i += ByteLenShort {
+		v := decodeSample(b[i:i+ByteLenShort], DSB, order)
+		if i > 0 {
+			sum += math.Abs(v - prev)
+		}
+		prev = v
+	}
+	return sum
+}
+
+// plausibility returns the fraction of the samples of b decoded in the byte order
+// which look like a signal, from 0 to 1.
+// Floating-point samples must be finite and of plausible magnitude. If the DFB samples at every
+// other index are zero, the data is rather DDB whose low words are zero and the score is halved.
+// DSB samples must be close to the previous sample, as swapped bytes or the mantissa bits of
+// floating-point data make large jumps.
+func plausibility(b []byte, dt DataType, order binary.ByteOrder) float64 {
+	bl := dt.ByteLen()
+	n := len(b) / bl
+	if n == 0 {
+		return 0
+	}
+	var (
+		ok    int
+		zeros [2]int
+		prev  float64
+	)
+	for i := 0; i < n; i++ {
+		v := decodeSample(b[i*bl:(i+1)*bl], dt, order)
+		if dt == DSB {
+			if i == 0 || math.Abs(v-prev) < maxPlausibleStep {
+				ok++
+			}
+			prev = v
+			continue
+		}
+		a := math.Abs(v)
+		if a == 0 {
+			zeros[i%2]++
+		}
+		if !math.IsNaN(v) && !math.IsInf(v, 0) && (a == 0 || (a >= minPlausibleMagnitude && a <= maxPlausibleMagnitude)) {
+			ok++
+		}
+	}
+	score := float64(ok) / float64(n)
+	if dt == DFB && n >= 4 {
+		even, odd := (n+1)/2, n/2
+		if (zeros[0] == even && zeros[1] < odd) || (zeros[1] == odd && zeros[0] < even) {
+			score /= 2
+		}
+	}
+	return score
+}
+
+// isZero reports whether b consists of zeros, which are the same signal whatever the data type is.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
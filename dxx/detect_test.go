@@ -0,0 +1,97 @@
+package dxx
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures in testdata are an impulse response of 2048 samples, a decaying lowpassed noise with a delayed onset.
+// It is stored at a peak of 0.9 in DFX and DDX, at a peak of 27000 in DSX, and at a peak of 12 in quiet_*.DSB,
+// whose swapped bytes still make small steps, so that only the tie-break of the byte orders tells them apart.
+var detectFixtures = []struct {
+	name  string
+	dt    DataType
+	order binary.ByteOrder
+}{
+	{"ir.DSA", DSA, nil},
+	{"ir.DFA", DFA, nil},
+	{"ir.DDA", DDA, nil},
+	{"ir_le.DSB", DSB, binary.LittleEndian},
+	{"ir_be.DSB", DSB, binary.BigEndian},
+	{"quiet_le.DSB", DSB, binary.LittleEndian},
+	{"quiet_be.DSB", DSB, binary.BigEndian},
+	{"ir_le.DFB", DFB, binary.LittleEndian},
+	{"ir_be.DFB", DFB, binary.BigEndian},
+	{"ir_le.DDB", DDB, binary.LittleEndian},
+	{"ir_be.DDB", DDB, binary.BigEndian},
+}
+
+func TestDetectFixtures(t *testing.T) {
+	for _, c := range detectFixtures {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", c.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			d, err := Detect(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.DataType != c.dt || d.ByteOrder != c.order {
+				t.Errorf("Detect: %v, want %v %v", d, c.dt, c.order)
+			}
+			if d.Confidence <= 0 {
+				t.Errorf("Detect: confidence %v of a signal", d.Confidence)
+			}
+		})
+	}
+}
+
+func TestDetectByteOrderFixtures(t *testing.T) {
+	for _, c := range detectFixtures {
+		if c.order == nil {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", c.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, err := DetectByteOrder(b, c.dt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order != c.order {
+				t.Errorf("DetectByteOrder: %v, want %v", order, c.order)
+			}
+			// Detect and DetectByteOrder agree on the byte order
+			if _, want := scoreBinary(b, c.dt); order != want {
+				t.Errorf("DetectByteOrder: %v, but Detect scores %v", order, want)
+			}
+		})
+	}
+
+	if _, err := DetectByteOrder([]byte{1, 2}, DSA); err != ErrNotBinary {
+		t.Errorf("DetectByteOrder of DSA: got %v, want ErrNotBinary", err)
+	}
+}
+
+func TestReadFromFileAutoFixtures(t *testing.T) {
+	for _, c := range detectFixtures {
+		t.Run(c.name, func(t *testing.T) {
+			data, d, err := ReadFromFileAuto(filepath.Join("testdata", c.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.DataType != c.dt || d.ByteOrder != c.order {
+				t.Errorf("ReadFromFileAuto: %v, want %v %v", d, c.dt, c.order)
+			}
+			if len(data) != 2048 {
+				t.Errorf("ReadFromFileAuto: %d samples, want 2048", len(data))
+			}
+		})
+	}
+}
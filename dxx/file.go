@@ -0,0 +1,223 @@
+package dxx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+var (
+	ErrOutOfRange = errors.New("sample range out of bounds")
+	ErrOpenWAV    = errors.New("WAV files cannot be opened for random access")
+)
+
+// File provides random, sample-indexed access to DXX data.
+// Binary data is decoded on demand. On Linux, files opened by Open are memory-mapped.
+// ASCII data has no fixed sample size, and compressed data cannot be accessed at random,
+// so they are decoded into memory when the File is created.
+// Like Reader, File does not rescale the samples.
+type File struct {
+	dt      DataType
+	order   binary.ByteOrder
+	ra      io.ReaderAt
+	data    []byte    // memory-mapped content of binary data
+	decoded []float64 // samples of ASCII or compressed data decoded into memory
+	length  int
+	close   func() error
+	closed  bool
+}
+
+// Open opens the .DXX file for random access.
+// This func determines the data type from the filename extension.
+// Compressed files are decompressed into memory in little-endian byte order,
+// except predicted data, which is read in the byte order it records.
+// WAV files are rejected with ErrOpenWAV; read them with ReadFileWithMeta.
+func Open(filename string) (*File, error) {
+	if isWAV(filename) {
+		return nil, fmt.Errorf("%s: %w: Open reads .DSA, .DFA, .DDA, .DSB, .DFB and .DDB files, optionally compressed as .gz, .zlib or .flate", filename, ErrOpenWAV)
+	}
+	dt, err := StringToDataType(ext(filename))
+	if err != nil {
+		return nil, err
+	}
+	if c, _ := CompressionOf(filename); c != NoCompression {
+		data, err := ReadFromFileWithOptions(filename, preserveOptions())
+		if err != nil {
+			return nil, err
+		}
+		return &File{dt: dt, order: binary.LittleEndian, decoded: data, length: len(data)}, nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	df, err := NewFile(f, info.Size(), dt)
+	if err != nil {
+		f.Close()
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = filename
+			return nil, pe
+		}
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if df.decoded != nil {
+		// all samples are already in memory.
+		return df, f.Close()
+	}
+
+	data, err := mmap(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if data == nil {
+		df.close = f.Close
+		return df, nil
+	}
+	df.data = data
+	df.close = func() error {
+		err := munmap(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	return df, nil
+}
+
+// NewFile returns a File which reads size bytes of DXX data from ra as specified data type.
+func NewFile(ra io.ReaderAt, size int64, dt DataType) (*File, error) {
+	f := &File{dt: dt, order: binary.LittleEndian, ra: ra}
+	switch dt {
+	case DSA, DFA, DDA:
+		r := NewReader(io.NewSectionReader(ra, 0, size), dt)
+		buf := make([]float64, 4096)
+		f.decoded = []float64{}
+		for {
+			n, err := r.ReadSamples(buf)
+			f.decoded = append(f.decoded, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		f.length = len(f.decoded)
+	case DSB, DFB, DDB:
+		if size%int64(dt.ByteLen()) != 0 {
+			return nil, ErrTruncated
+		}
+		f.length = int(size / int64(dt.ByteLen()))
+	default:
+		return nil, ErrUnknownDataType
+	}
+	return f, nil
+}
+
+// DataType returns the data type of the file.
+func (f *File) DataType() DataType {
+	return f.dt
+}
+
+// SetByteOrder sets the byte order of binary data. The default is binary.LittleEndian.
+func (f *File) SetByteOrder(order binary.ByteOrder) {
+	f.order = order
+}
+
+// Len returns the number of samples in the file.
+func (f *File) Len() int {
+	return f.length
+}
+
+// ReadAt reads len(dst) samples into dst starting at sample offset off.
+// It follows the semantics of io.ReaderAt: when fewer than len(dst) samples are read,
+// ReadAt returns io.EOF along with the number of samples read.
+// After Close, ReadAt returns fs.ErrClosed.
+func (f *File) ReadAt(dst []float64, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if off < 0 {
+		return 0, ErrOutOfRange
+	}
+	if off >= int64(f.length) {
+		return 0, io.EOF
+	}
+	n := len(dst)
+	if rest := int64(f.length) - off; int64(n) > rest {
+		n = int(rest)
+	}
+
+	if f.decoded != nil {
+		copy(dst, f.decoded[off:off+int64(n)])
+	} else if err := f.readBinaryAt(dst[:n], off); err != nil {
+		return 0, err
+	}
+	if n < len(dst) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *File) readBinaryAt(dst []float64, off int64) error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	bl := f.dt.ByteLen()
+	var b []byte
+	if f.data != nil {
+		b = f.data[off*int64(bl) : (off+int64(len(dst)))*int64(bl)]
+	} else {
+		b = make([]byte, len(dst)*bl)
+		// io.ReaderAt may return io.EOF along with all the bytes at the end of the data.
+		if n, err := f.ra.ReadAt(b, off*int64(bl)); n < len(b) {
+			if err == nil || err == io.EOF {
+				return ErrTruncated
+			}
+			return err
+		}
+	}
+	decodeSamples(dst, b, f.dt, f.order)
+	return nil
+}
+
+// Slice returns n samples starting at sample start.
+// If the range exceeds the file, Slice returns ErrOutOfRange.
+// After Close, Slice returns fs.ErrClosed.
+func (f *File) Slice(start, n int) ([]float64, error) {
+	if f.closed {
+		return nil, fs.ErrClosed
+	}
+	if start < 0 || n < 0 || start+n > f.length {
+		return nil, ErrOutOfRange
+	}
+	data := make([]float64, n)
+	if _, err := f.ReadAt(data, int64(start)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Close releases the resources of the file.
+// The mapped memory is unmapped, so the samples cannot be read after Close.
+func (f *File) Close() error {
+	f.closed = true
+	// drop the references to the unmapped memory and the closed file
+	f.data, f.ra, f.decoded = nil, nil, nil
+	if f.close == nil {
+		return nil
+	}
+	err := f.close()
+	f.close = nil
+	return err
+}
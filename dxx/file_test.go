@@ -0,0 +1,105 @@
+package dxx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+func TestFileReadAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	data := []float64{1, -2, 3, -4, 5}
+	for _, dt := range dataTypes {
+		t.Run(dt.String(), func(t *testing.T) {
+			filename := filepath.Join(dir, "x."+dt.String())
+			if err := WriteToFileWithOptions(filename, data, &Options{Scaler: &conv.Scaler{Mode: conv.Preserve}}); err != nil {
+				t.Fatal(err)
+			}
+			f, err := Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := f.Slice(1, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0] != -2 || got[2] != -4 {
+				t.Fatalf("Slice(1, 3) = %v, want [-2 3 -4]", got)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.ReadAt(make([]float64, 2), 0); !errors.Is(err, fs.ErrClosed) {
+				t.Errorf("ReadAt after Close: got %v, want fs.ErrClosed", err)
+			}
+			if _, err := f.Slice(0, 2); !errors.Is(err, fs.ErrClosed) {
+				t.Errorf("Slice after Close: got %v, want fs.ErrClosed", err)
+			}
+			if err := f.Close(); err != nil {
+				t.Errorf("second Close: %v", err)
+			}
+		})
+	}
+}
+
+// eofReaderAt returns io.EOF along with the bytes which reach the end of the data, as io.ReaderAt permits.
+type eofReaderAt struct {
+	r *bytes.Reader
+}
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	if err == nil && off+int64(n) == r.r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestFileReadAtEOF(t *testing.T) {
+	data := []float64{1, -2, 3, -4, 5}
+	for _, dt := range []DataType{DSB, DFB, DDB} {
+		b := encode(t, dt, data, preserveOptions())
+		f, err := NewFile(eofReaderAt{bytes.NewReader(b)}, int64(len(b)), dt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]float64, len(data))
+		if n, err := f.ReadAt(got, 0); n != len(data) || err != nil {
+			t.Fatalf("%v: ReadAt of all the samples = %d, %v, want %d, nil", dt, n, err, len(data))
+		}
+		assertSameFloat64s(t, got, data)
+		if got, err := f.Slice(3, 2); err != nil || got[0] != -4 || got[1] != 5 {
+			t.Errorf("%v: Slice(3, 2) = %v, %v, want [-4 5]", dt, got, err)
+		}
+
+		// the data is shorter than the size given
+		f, err = NewFile(eofReaderAt{bytes.NewReader(b[:len(b)-dt.ByteLen()])}, int64(len(b)), dt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.ReadAt(got, 0); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%v: ReadAt beyond the data: got %v, want ErrTruncated", dt, err)
+		}
+	}
+}
+
+func TestOpenWAV(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sound.wav")
+	if err := WriteToFile(filename, []float64{0.5, -0.5}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Open(filename)
+	if !errors.Is(err, ErrOpenWAV) {
+		t.Fatalf("Open of a WAV file: got %v, want ErrOpenWAV", err)
+	}
+	if !strings.Contains(err.Error(), filename) || !strings.Contains(err.Error(), ".DDB") {
+		t.Errorf("the error %q does not tell the file and the supported inputs", err)
+	}
+}
@@ -2,14 +2,12 @@ package dxx
 
 import (
 	"bufio"
-	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
+	"io/fs"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/tetsuzawa/go-soundlib/conv"
@@ -26,6 +24,7 @@ const (
 
 var (
 	ErrUnknownDataType = errors.New("unknown data type")
+	ErrTruncated       = errors.New("binary data ends in the middle of a sample")
 )
 
 // DataType is type of DXX.
@@ -62,9 +61,10 @@ func (dt DataType) String() string {
 }
 
 // StringToDataType determines data type from specified string.
+// The comparison is case-insensitive, so "ddb" is DDB.
 // If the specified string is invalid, this func returns error.
 func StringToDataType(s string) (DataType, error) {
-	switch s {
+	switch strings.ToUpper(s) {
 	case "DSA":
 		return DSA, nil
 	case "DFA":
@@ -102,6 +102,11 @@ func (dt DataType) BitLen() int {
 	}
 }
 
+// IsBinary reports whether the data type is a binary type.
+func (dt DataType) IsBinary() bool {
+	return dt == DSB || dt == DFB || dt == DDB
+}
+
 // ByteLen returns the byte length of data type.
 func (dt DataType) ByteLen() int {
 	switch dt {
@@ -122,284 +127,354 @@ func (dt DataType) ByteLen() int {
 	}
 }
 
-// Read reads data from reader as specified data type.
+// Read reads up to length samples from reader as specified data type.
+// If length is negative, Read reads until the end of the data.
+// ASCII data may contain blank lines and comment lines starting with CommentPrefix.
+// A malformed ASCII line is reported as *ParseError, and binary data ending
+// in the middle of a sample is reported as ErrTruncated.
 // The return type is []float64 to make the data easier to handle.
+// DSX and DFX samples are normalised to an amplitude of 10000, and DDX samples are returned as stored.
+// Use ReadWithOptions with a conv.Scaler to keep the stored values or to choose another scaling policy.
 func Read(r io.Reader, dt DataType, length int) ([]float64, error) {
-	switch dt {
-	case DSA:
-		i16s, err := readDSA(r, length)
-		if err != nil {
-			return nil, err
-		}
-		return conv.Int16sToFloat64s(i16s), nil
-	case DFA:
-		f32s, err := readDFA(r, length)
-		if err != nil {
-			return nil, err
-		}
-		return conv.Float32sToFloat64s(f32s), nil
-	case DDA:
-		f64s, err := readDDA(r, length)
-		if err != nil {
-			return nil, err
-		}
-		return f64s, nil
-	case DSB:
-		i16s, err := readDSB(r, length)
-		if err != nil {
-			return nil, err
-		}
-		return conv.Int16sToFloat64s(i16s), nil
-	case DFB:
-		f32s, err := readDFB(r, length)
-		if err != nil {
-			return nil, err
-		}
-		return conv.Float32sToFloat64s(f32s), nil
-	case DDB:
-		f64s, err := readDDB(r, length)
-		if err != nil {
-			return nil, err
-		}
-		return f64s, nil
-	default:
-		return nil, ErrUnknownDataType
-	}
+	return ReadWithOptions(r, dt, length, nil)
 }
 
 // ReadFromFile reads .DXX file.
 // This func determines the data type from the filename extension and reads that data.
 // The return type is []float64 to make the data easier to handle.
+// Files with .wav extension are read as WAV. Multichannel WAV data is returned interleaved.
 func ReadFromFile(filename string) ([]float64, error) {
+	return ReadFromFileWithOptions(filename, nil)
+}
+
+// ReadFromFileWithOptions reads .DXX file like ReadFromFile and converts the samples as specified by opts.
+// The options are ignored for WAV files.
+func ReadFromFileWithOptions(filename string, opts *Options) ([]float64, error) {
+	return ReadFromFSWithOptions(osFS{}, filename, opts)
+}
+
+// readFile opens the DXX file and calls read with the buffered file, its data type and
+// the number of samples. The number of samples is -1 for ASCII data.
+// The errors returned by read are annotated with filename.
+// Compressed files are decompressed, and the predictor is inverted for binary data.
+func readFile(filename string, opts *Options, read func(r io.Reader, dt DataType, length int) error) error {
+	return readFileFS(osFS{}, filename, opts, read)
+}
+
+// readFileFS is readFile for the file name in fsys.
+func readFileFS(fsys fs.FS, filename string, opts *Options, read func(r io.Reader, dt DataType, length int) error) error {
 	dt, err := StringToDataType(ext(filename))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	b, err := ioutil.ReadFile(filename)
-	r := bytes.NewReader(b)
-	length := r.Len() / dt.ByteLen()
-	return Read(r, dt, length)
-}
-
-func readDSA(r io.Reader, length int) ([]int16, error) {
-	sc := bufio.NewScanner(r)
-	data := make([]int16, length)
-	for i := range data {
-		sc.Scan()
-		v, err := strconv.ParseInt(sc.Text(), 10, 16)
-		if err != nil {
-			return nil, err
+	f, err := openData(fsys, filename)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	defer f.Close()
+	// the number of samples is known only for uncompressed binary data.
+	length := -1
+	if dt.IsBinary() && f.size >= 0 {
+		if f.size%int64(dt.ByteLen()) != 0 {
+			return fmt.Errorf("%s: %w", filename, ErrTruncated)
 		}
-		data[i] = int16(v)
+		length = int(f.size) / dt.ByteLen()
 	}
-	return data, nil
+	var r io.Reader = f
+	if dt.IsBinary() && f.pred != NoPredictor {
+		// the samples are returned in the byte order of opts even if the data records another.
+		order := opts.byteOrder()
+		if f.order != nil {
+			order = f.order
+		}
+		r = newUnpredictReader(r, dt, order, opts.byteOrder(), f.pred)
+	}
+	if err := read(bufio.NewReader(r), dt, length); err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = filename
+			return pe
+		}
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
 }
 
-func readDFA(r io.Reader, length int) ([]float32, error) {
-	sc := bufio.NewScanner(r)
-	data := make([]float32, length)
-	for i := range data {
-		sc.Scan()
-		v, err := strconv.ParseFloat(sc.Text(), 32)
-		if err != nil {
-			return nil, err
-		}
-		data[i] = float32(v)
+// capacity returns the capacity to allocate for length samples.
+// A negative length means unknown.
+func capacity(length int) int {
+	if length < 0 {
+		return 0
 	}
-	return data, nil
+	return length
 }
 
-func readDDA(r io.Reader, length int) ([]float64, error) {
-	sc := bufio.NewScanner(r)
-	data := make([]float64, length)
-	for i := range data {
-		sc.Scan()
-		v, err := strconv.ParseFloat(sc.Text(), 64)
+func readDSA(r io.Reader, length int) ([]int16, error) {
+	sc := newASCIIScanner(r, DSA)
+	data := make([]int16, 0, capacity(length))
+	for length < 0 || len(data) < length {
+		v, err := sc.next()
 		if err != nil {
+			if err == io.EOF {
+				return data, nil
+			}
 			return nil, err
 		}
-		data[i] = v
+		data = append(data, int16(v))
 	}
 	return data, nil
 }
 
-func readDSB(r io.Reader, length int) ([]int16, error) {
-	buf := make([]byte, ByteLenShort)
-	data := make([]int16, length)
-	for i := range data {
-		_, err := io.ReadFull(r, buf)
+func readDFA(r io.Reader, length int) ([]float32, error) {
+	sc := newASCIIScanner(r, DFA)
+	data := make([]float32, 0, capacity(length))
+	for length < 0 || len(data) < length {
+		v, err := sc.next()
 		if err != nil {
 			if err == io.EOF {
 				return data, nil
 			}
-			return data, err
-		}
-		v, err := conv.BytesToInt16(buf)
-		if err != nil {
-			return data, err
+			return nil, err
 		}
-		data[i] = v
+		data = append(data, float32(v))
 	}
 	return data, nil
 }
 
-func readDFB(r io.Reader, length int) ([]float32, error) {
-	buf := make([]byte, ByteLenFloat)
-	data := make([]float32, length)
-	for i := range data {
-		_, err := io.ReadFull(r, buf)
+func readDDA(r io.Reader, length int) ([]float64, error) {
+	sc := newASCIIScanner(r, DDA)
+	data := make([]float64, 0, capacity(length))
+	for length < 0 || len(data) < length {
+		v, err := sc.next()
 		if err != nil {
 			if err == io.EOF {
 				return data, nil
 			}
-			return data, err
-		}
-		v, err := conv.BytesToFloat32(buf)
-		if err != nil {
-			return data, err
+			return nil, err
 		}
-		data[i] = v
+		data = append(data, v)
 	}
 	return data, nil
 }
 
-func readDDB(r io.Reader, length int) ([]float64, error) {
-	buf := make([]byte, ByteLenDouble)
-	data := make([]float64, length)
-	for i := range data {
-		_, err := io.ReadFull(r, buf)
-		if err != nil {
-			if err == io.EOF {
-				return data, nil
-			}
-			return data, err
+// chunkSamples is the number of binary samples converted at once.
+const chunkSamples = 4096
+
+// readBinary reads up to length samples of byte length bl in chunks.
+// If length is negative, readBinary reads until EOF.
+// decode is called with the bytes of whole samples.
+// If the data ends in the middle of a sample, readBinary returns ErrTruncated.
+func readBinary(r io.Reader, bl, length int, decode func(b []byte)) error {
+	chunk := make([]byte, chunkSamples*bl)
+	for n := 0; length < 0 || n < length; {
+		k := chunkSamples
+		if length >= 0 && length-n < k {
+			k = length - n
 		}
-		v, err := conv.BytesToFloat64(buf)
-		if err != nil {
-			return data, err
+		m, err := io.ReadFull(r, chunk[:k*bl])
+		decode(chunk[:m-m%bl])
+		n += m / bl
+		switch {
+		case err == io.EOF:
+			return nil
+		case err == io.ErrUnexpectedEOF && m%bl == 0:
+			return nil
+		case err == io.ErrUnexpectedEOF:
+			return ErrTruncated
+		case err != nil:
+			return err
 		}
-		data[i] = v
 	}
-	return data, nil
+	return nil
+}
+
+func readDSB(r io.Reader, length int, order binary.ByteOrder) ([]int16, error) {
+	data := make([]int16, 0, capacity(length))
+	err := readBinary(r, ByteLenShort, length, func(b []byte) {
+		i := len(data)
+		data = append(data, make([]int16, len(b)/ByteLenShort)...)
+		conv.DecodeInt16s(data[i:], b, order)
+	})
+	return data, err
+}
+
+func readDFB(r io.Reader, length int, order binary.ByteOrder) ([]float32, error) {
+	data := make([]float32, 0, capacity(length))
+	err := readBinary(r, ByteLenFloat, length, func(b []byte) {
+		i := len(data)
+		data = append(data, make([]float32, len(b)/ByteLenFloat)...)
+		conv.DecodeFloat32s(data[i:], b, order)
+	})
+	return data, err
+}
+
+func readDDB(r io.Reader, length int, order binary.ByteOrder) ([]float64, error) {
+	data := make([]float64, 0, capacity(length))
+	err := readBinary(r, ByteLenDouble, length, func(b []byte) {
+		i := len(data)
+		data = append(data, make([]float64, len(b)/ByteLenDouble)...)
+		conv.DecodeFloat64s(data[i:], b, order)
+	})
+	return data, err
 }
 
 // Writes writes data to writer as specified data type.
 // The return type is []float64 to make the data easier to handle.
+// DSX samples are normalised to an amplitude of 32767, DFX samples to 10000, and DDX samples are stored as they are.
+// Use WriteWithOptions with a conv.Scaler to store the values as they are or to choose another scaling policy.
 func Write(w io.Writer, dt DataType, data []float64) error {
-	buf := &bytes.Buffer{}
-	var err error
-	switch dt {
-	case DSA:
-		err = writeDSA(buf, conv.Float64sToInt16s(data))
-	case DFA:
-		err = writeDFA(buf, conv.Float64sToFloat32s(data))
-	case DDA:
-		err = writeDDA(buf, data)
-	case DSB:
-		err = writeDSB(buf, conv.Float64sToInt16s(data))
-	case DFB:
-		err = writeDFB(buf, conv.Float64sToFloat32s(data))
-	case DDB:
-		err = writeDDB(buf, data)
-	default:
-		err = ErrUnknownDataType
-	}
-	if err != nil {
-		return err
-	}
-	_, err = io.Copy(w, buf)
-	return err
+	return WriteWithOptions(w, dt, data, nil)
 }
 
 // Writes writes data to .DXX file.
 // This func determines the data type from the filename extension and writes the data to the file.
 // The return type is []float64 to make the data easier to handle.
+// Files with .wav extension are written as WAV of DefaultWAVFormat.
+// The file is written atomically: the data goes to a temporary file in the same directory,
+// which is synced and renamed to filename only if everything is written. See CreateAtomic.
+// Files with a compression extension, e.g. .DDB.gz, are compressed. See Compression.
 func WriteToFile(filename string, data []float64) error {
-	f, err := os.Create(filename)
-	defer f.Close()
-	if err != nil {
-		return err
+	return WriteToFileWithOptions(filename, data, nil)
+}
+
+// WriteToFileWithOptions writes data to .DXX file like WriteToFile and converts the samples as specified by opts.
+// Only opts.NoOverwrite is used for WAV files.
+func WriteToFileWithOptions(filename string, data []float64, opts *Options) error {
+	if isWAV(filename) {
+		return writeWAVFile(filename, DefaultWAVFormat, data, opts)
 	}
 
 	dt, err := StringToDataType(ext(filename))
 	if err != nil {
 		return err
 	}
-	return Write(f, dt, data)
-}
-
-func writeDSA(w io.Writer, data []int16) error {
-	for _, v := range data {
-		if _, err := fmt.Fprintf(w, "%d\n", v); err != nil {
-			return err
-		}
+	pred, err := opts.predictorFor(filename, dt)
+	if err != nil {
+		return err
 	}
-	return nil
-}
-
-func writeDFA(w io.Writer, data []float32) error {
-	for _, v := range data {
-		if _, err := fmt.Fprintf(w, "%e\n", v); err != nil {
+	return writeFileAtomic(filename, opts, pred, func(w io.Writer) error {
+		if pred == NoPredictor {
+			return WriteWithOptions(w, dt, data, opts)
+		}
+		pw := newPredictWriter(w, dt, opts.byteOrder(), pred)
+		if err := WriteWithOptions(pw, dt, data, opts); err != nil {
 			return err
 		}
-	}
-	return nil
+		return pw.Close()
+	})
 }
 
-func writeDDA(w io.Writer, data []float64) error {
+func writeDSA(w io.Writer, data []int16) error {
 	for _, v := range data {
-		if _, err := fmt.Fprintf(w, "%e\n", v); err != nil {
+		if _, err := fmt.Fprintf(w, "%d\n", v); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func writeDSB(w io.Writer, data []int16) error {
-	for _, v := range data {
-		buf, err := conv.Int16ToBytes(v)
-		if err != nil {
-			return err
+func writeDSB(w io.Writer, data []int16, order binary.ByteOrder) error {
+	buf := make([]byte, chunkSamples*ByteLenShort)
+	for len(data) > 0 {
+		k := len(data)
+		if k > chunkSamples {
+			k = chunkSamples
 		}
-		_, err = w.Write(buf)
-		if err != nil {
+		conv.EncodeInt16s(buf, data[:k], order)
+		if _, err := w.Write(buf[:k*ByteLenShort]); err != nil {
 			return err
 		}
+		data = data[k:]
 	}
 	return nil
 }
 
-func writeDFB(w io.Writer, data []float32) error {
-	for _, v := range data {
-		buf, err := conv.Float32ToBytes(v)
-		if err != nil {
-			return err
+func writeDFB(w io.Writer, data []float32, order binary.ByteOrder) error {
+	buf := make([]byte, chunkSamples*ByteLenFloat)
+	for len(data) > 0 {
+		k := len(data)
+		if k > chunkSamples {
+			k = chunkSamples
 		}
-		_, err = w.Write(buf)
-		if err != nil {
+		conv.EncodeFloat32s(buf, data[:k], order)
+		if _, err := w.Write(buf[:k*ByteLenFloat]); err != nil {
 			return err
 		}
+		data = data[k:]
 	}
 	return nil
 }
 
-func writeDDB(w io.Writer, data []float64) error {
-	for _, v := range data {
-		buf, err := conv.Float64ToBytes(v)
-		if err != nil {
-			return err
+func writeDDB(w io.Writer, data []float64, order binary.ByteOrder) error {
+	buf := make([]byte, chunkSamples*ByteLenDouble)
+	for len(data) > 0 {
+		k := len(data)
+		if k > chunkSamples {
+			k = chunkSamples
 		}
-		_, err = w.Write(buf)
-		if err != nil {
+		conv.EncodeFloat64s(buf, data[:k], order)
+		if _, err := w.Write(buf[:k*ByteLenDouble]); err != nil {
 			return err
 		}
+		data = data[k:]
 	}
 	return nil
 }
 
 // ext returns the path of extension *without* dot.
 // eg: ext(/path/to/file.aaa) -> aaa
+// The compression extension is skipped: ext(file.DDB.gz) -> DDB
 func ext(path string) string {
+	_, path = CompressionOf(path)
 	return strings.TrimPrefix(filepath.Ext(path), ".")
 }
+
+// decodeSamples decodes len(dst) binary samples of the data type from b.
+func decodeSamples(dst []float64, b []byte, dt DataType, order binary.ByteOrder) {
+	if dt == DDB {
+		conv.DecodeFloat64s(dst, b, order)
+		return
+	}
+	bl := dt.ByteLen()
+	for i := range dst {
+		dst[i] = decodeSample(b[i*bl:], dt, order)
+	}
+}
+
+// decodeSample decodes a binary sample of the data type from b.
+func decodeSample(b []byte, dt DataType, order binary.ByteOrder) float64 {
+	switch dt {
+	case DSB:
+		return float64(conv.DecodeInt16(b, order))
+	case DFB:
+		return float64(conv.DecodeFloat32(b, order))
+	default:
+		return conv.DecodeFloat64(b, order)
+	}
+}
+
+// encodeSamples encodes src into b as binary samples of the data type.
+func encodeSamples(b []byte, src []float64, dt DataType, order binary.ByteOrder) {
+	if dt == DDB {
+		conv.EncodeFloat64s(b, src, order)
+		return
+	}
+	bl := dt.ByteLen()
+	for i, v := range src {
+		encodeSample(b[i*bl:], v, dt, order)
+	}
+}
+
+// encodeSample encodes a binary sample of the data type into b.
+// Values are rounded and saturated for DSB.
+func encodeSample(b []byte, v float64, dt DataType, order binary.ByteOrder) {
+	switch dt {
+	case DSB:
+		conv.EncodeInt16(b, saturateInt16(v), order)
+	case DFB:
+		conv.EncodeFloat32(b, float32(v), order)
+	default:
+		conv.EncodeFloat64(b, v, order)
+	}
+}
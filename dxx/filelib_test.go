@@ -0,0 +1,185 @@
+package dxx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+var dataTypes = []DataType{DSA, DFA, DDA, DSB, DFB, DDB}
+
+func TestReadFromFileParseError(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		content string
+		line    int
+		text    string
+		err     error
+	}{
+		{"bad.DDA", "1\n-2.5\nx\n3\n", 3, "x", strconv.ErrSyntax},
+		// comment lines and blank lines are counted
+		{"comment.DFA", "# sampling rate 48000\n\n1\n# end\n1e\n", 5, "1e", strconv.ErrSyntax},
+		// the line ending is not part of the text
+		{"crlf.DDA", "1\r\n2\r\n3.5.1\r\n", 3, "3.5.1", strconv.ErrSyntax},
+		{"range.DSA", "32767\n-32768\n32768\n", 3, "32768", strconv.ErrRange},
+	} {
+		filename := filepath.Join(t.TempDir(), c.name)
+		if err := os.WriteFile(filename, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ReadFromFile(filename)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: got %v, want a *ParseError", c.name, err)
+			continue
+		}
+		if pe.File != filename || pe.Line != c.line || pe.Text != c.text {
+			t.Errorf("%s: got file %q, line %d, text %q, want %q, %d, %q", c.name, pe.File, pe.Line, pe.Text, filename, c.line, c.text)
+		}
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: %v does not wrap %v", c.name, err, c.err)
+		}
+		if prefix := filename + ":" + strconv.Itoa(c.line) + ":"; !strings.HasPrefix(err.Error(), prefix) {
+			t.Errorf("%s: error %q does not start with %q", c.name, err, prefix)
+		}
+	}
+
+	// without a file, the error has no file name
+	_, err := Read(strings.NewReader("1\n\nx\n"), DDA, -1)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.File != "" || pe.Line != 3 {
+		t.Fatalf("Read: got %v, want a *ParseError at line 3 without a file", err)
+	}
+	if !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("Read: error %q does not start with the line", err)
+	}
+}
+
+func TestReadFromFileTruncated(t *testing.T) {
+	values := []float64{1, -2, 3}
+	for _, dt := range []DataType{DSB, DFB, DDB} {
+		var buf bytes.Buffer
+		if err := WriteWithOptions(&buf, dt, values, &Options{Scaler: &conv.Scaler{}}); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()[:buf.Len()-1]
+
+		if _, err := ReadWithOptions(bytes.NewReader(data), dt, -1, &Options{Scaler: &conv.Scaler{}}); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%v: Read got %v, want ErrTruncated", dt, err)
+		}
+
+		dir := t.TempDir()
+		filename := filepath.Join(dir, "signal."+dt.String())
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// a compressed file, whose size is known only at the end
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		gzname := filename + ".gz"
+		if err := os.WriteFile(gzname, gz.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{filename, gzname} {
+			_, err := ReadFromFile(name)
+			if !errors.Is(err, ErrTruncated) {
+				t.Errorf("%s: got %v, want ErrTruncated", filepath.Base(name), err)
+				continue
+			}
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				t.Errorf("%s: binary data reported as %v", filepath.Base(name), pe)
+			}
+			if !strings.HasPrefix(err.Error(), name+":") {
+				t.Errorf("%s: error %q does not start with the file name", filepath.Base(name), err)
+			}
+		}
+	}
+}
+
+// benchmarkData returns 1 second of a 440 Hz sine at 48 kHz in the range of DSA and DSB.
+func benchmarkData() []float64 {
+	data := make([]float64, 48000)
+	for i := range data {
+		data[i] = 30000 * math.Sin(2*math.Pi*440*float64(i)/48000)
+	}
+	return data
+}
+
+type benchmarkOption struct {
+	name string
+	opts *Options
+}
+
+// benchmarkOptions are the byte orders of the benchmarks of the data type.
+func benchmarkOptions(dt DataType) []benchmarkOption {
+	if !dt.IsBinary() {
+		return []benchmarkOption{{"", nil}}
+	}
+	return []benchmarkOption{
+		{"/LittleEndian", nil},
+		{"/BigEndian", &Options{ByteOrder: binary.BigEndian}},
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	data := benchmarkData()
+	for _, dt := range dataTypes {
+		for _, o := range benchmarkOptions(dt) {
+			name, opts := o.name, o.opts
+			var buf bytes.Buffer
+			if err := WriteWithOptions(&buf, dt, data, opts); err != nil {
+				b.Fatal(err)
+			}
+			encoded := buf.Bytes()
+			b.Run(dt.String()+name, func(b *testing.B) {
+				b.SetBytes(int64(len(encoded)))
+				for i := 0; i < b.N; i++ {
+					got, err := ReadWithOptions(bytes.NewReader(encoded), dt, -1, opts)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(got) != len(data) {
+						b.Fatalf("read %d samples, want %d", len(got), len(data))
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	data := benchmarkData()
+	for _, dt := range dataTypes {
+		for _, o := range benchmarkOptions(dt) {
+			name, opts := o.name, o.opts
+			var buf bytes.Buffer
+			if err := WriteWithOptions(&buf, dt, data, opts); err != nil {
+				b.Fatal(err)
+			}
+			size := int64(buf.Len())
+			b.Run(dt.String()+name, func(b *testing.B) {
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					if err := WriteWithOptions(io.Discard, dt, data, opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package dxx
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ReadFromFS reads the .DXX file name in fsys like ReadFromFile.
+// fsys may be an embed.FS, a *zip.Reader, an fstest.MapFS or any other fs.FS,
+// and name follows the path rules of fs.FS, e.g. "SLTF/SLTF_0_L.DDB".
+func ReadFromFS(fsys fs.FS, name string) ([]float64, error) {
+	return ReadFromFSWithOptions(fsys, name, nil)
+}
+
+// ReadFromFSWithOptions reads the .DXX file name in fsys like ReadFromFileWithOptions.
+func ReadFromFSWithOptions(fsys fs.FS, name string, opts *Options) ([]float64, error) {
+	if isWAV(name) {
+		data, _, err := readWAVFileFS(fsys, name)
+		return data, err
+	}
+
+	var data []float64
+	err := readFileFS(fsys, name, opts, func(r io.Reader, dt DataType, length int) (err error) {
+		data, err = ReadWithOptions(r, dt, length, opts)
+		return err
+	})
+	return data, err
+}
+
+// osFS is the file system of the operating system, which opens names as os.Open does.
+// Unlike os.DirFS, it accepts any path, so the path based functions can share the code of the fs.FS ones.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
@@ -0,0 +1,97 @@
+package dxx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+// encode returns data stored as the data type with opts.
+func encode(t *testing.T, dt DataType, data []float64, opts *Options) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteWithOptions(&buf, dt, data, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadFromFS(t *testing.T) {
+	values := []float64{1000, -500, 250, 0}
+	preserve := &Options{Scaler: &conv.Scaler{}}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(encode(t, DDB, values, nil))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var wav bytes.Buffer
+	if err := WriteWAV(&wav, WAVFormat{SampleRate: 8000, Channels: 1, BitsPerSample: 16}, []float64{0.5, -0.25}); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"a.DSA":                 {Data: []byte("# comment\n1000\n-500\n250\n0\n")},
+		"sub/b.DSB":             {Data: encode(t, DSB, values, preserve)},
+		"sub/dir/c.DFB":         {Data: encode(t, DFB, values, preserve)},
+		"d.DDB.gz":              {Data: gz.Bytes()},
+		"e.wav":                 {Data: wav.Bytes()},
+		"bad.DDA":               {Data: []byte("1\nx\n")},
+		"short.DDB":             {Data: encode(t, DDB, values, nil)[:7]},
+		"sub/b.DSB" + MetaExt:   {Data: []byte(`{"sampling_rate": 48000, "creator": "test"}`)},
+		"sub/dir/c.DFB.unknown": {Data: []byte("not read")},
+	}
+
+	for _, name := range []string{"a.DSA", "sub/b.DSB", "sub/dir/c.DFB", "d.DDB.gz"} {
+		got, err := ReadFromFSWithOptions(fsys, name, preserve)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		assertSameFloat64s(t, got, values)
+	}
+	// DSX data is normalised by default, as by ReadFromFile
+	got, err := ReadFromFS(fsys, "sub/b.DSB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameFloat64s(t, got, []float64{10000, -5000, 2500, 0})
+
+	got, err = ReadFromFS(fsys, "e.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameFloat64s(t, got, []float64{0.5, -0.25})
+
+	data, m, err := ReadFileWithMetaFSWithOptions(fsys, "sub/b.DSB", preserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameFloat64s(t, data, values)
+	if m == nil || m.SamplingRate != 48000 || m.Creator != "test" {
+		t.Errorf("metadata of sub/b.DSB: got %+v", m)
+	}
+	if _, m, err := ReadFileWithMetaFS(fsys, "a.DSA"); err != nil || m != nil {
+		t.Errorf("a.DSA without a sidecar: got %+v, %v, want nil, nil", m, err)
+	}
+	if _, m, err := ReadFileWithMetaFS(fsys, "e.wav"); err != nil || m == nil || m.SamplingRate != 8000 || m.Channels != 1 {
+		t.Errorf("metadata of e.wav: got %+v, %v", m, err)
+	}
+
+	if _, err := ReadFromFS(fsys, "missing.DDB"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing.DDB: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := ReadFromFS(fsys, "sub/dir/c.DFB.unknown"); !errors.Is(err, ErrUnknownDataType) {
+		t.Errorf("an unknown extension: got %v, want ErrUnknownDataType", err)
+	}
+	var pe *ParseError
+	if _, err := ReadFromFS(fsys, "bad.DDA"); !errors.As(err, &pe) || pe.File != "bad.DDA" || pe.Line != 2 {
+		t.Errorf("bad.DDA: got %v, want a *ParseError at bad.DDA:2", err)
+	}
+	if _, err := ReadFromFS(fsys, "short.DDB"); !errors.Is(err, ErrTruncated) {
+		t.Errorf("short.DDB: got %v, want ErrTruncated", err)
+	}
+}
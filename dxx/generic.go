@@ -0,0 +1,109 @@
+package dxx
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+// Sample is the constraint of the sample types: int16, float32 and float64.
+type Sample = conv.Sample
+
+// ReadAs reads data of any data type from reader as samples of type T.
+// The values are returned as stored, without any scaling. Values converted to int16 are rounded and saturated.
+// If T is the sample type of dt, no conversion takes place.
+// Binary data is in little-endian byte order.
+func ReadAs[T Sample](r io.Reader, dt DataType, length int) ([]T, error) {
+	return readAs[T](r, dt, length, binary.LittleEndian)
+}
+
+func readAs[T Sample](r io.Reader, dt DataType, length int, order binary.ByteOrder) ([]T, error) {
+	switch dt {
+	case DSA, DSB:
+		data, err := readInt16s(r, dt, length, order)
+		if err != nil {
+			return nil, err
+		}
+		return convertSamples[int16, T](data), nil
+	case DFA, DFB:
+		data, err := readFloat32s(r, dt, length, order)
+		if err != nil {
+			return nil, err
+		}
+		return convertSamples[float32, T](data), nil
+	case DDA, DDB:
+		data, err := readFloat64s(r, dt, length, order)
+		if err != nil {
+			return nil, err
+		}
+		return convertSamples[float64, T](data), nil
+	default:
+		return nil, ErrUnknownDataType
+	}
+}
+
+// WriteAs writes samples of type T to writer as any data type.
+// The values are stored as they are, rounded and saturated for DSA and DSB.
+// If T is the sample type of dt, no conversion takes place.
+// Binary data is in little-endian byte order.
+func WriteAs[T Sample](w io.Writer, dt DataType, data []T) error {
+	return writeAs(w, dt, data, binary.LittleEndian)
+}
+
+func writeAs[T Sample](w io.Writer, dt DataType, data []T, order binary.ByteOrder) error {
+	switch dt {
+	case DSA, DSB:
+		return writeInt16s(w, dt, convertSamples[T, int16](data), order)
+	case DFA, DFB:
+		return writeFloat32s(w, dt, convertSamples[T, float32](data), order, shortestFormat)
+	case DDA, DDB:
+		return writeFloat64s(w, dt, convertSamples[T, float64](data), order, shortestFormat)
+	default:
+		return ErrUnknownDataType
+	}
+}
+
+// ReadFromFileAs reads the file as samples of type T.
+// The data type is determined by the extension of the file.
+// See ReadAs for the conversion of the values.
+func ReadFromFileAs[T Sample](filename string) ([]T, error) {
+	if isWAV(filename) {
+		data, _, err := readWAVFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return convertSamples[float64, T](data), nil
+	}
+	var data []T
+	err := readFile(filename, nil, func(r io.Reader, dt DataType, length int) (err error) {
+		data, err = ReadAs[T](r, dt, length)
+		return err
+	})
+	return data, err
+}
+
+// WriteToFileAs writes samples of type T to the file.
+// The data type is determined by the extension of the file.
+// See WriteAs for the conversion of the values. The file is written atomically like WriteToFile.
+func WriteToFileAs[T Sample](filename string, data []T) error {
+	if isWAV(filename) {
+		return writeWAVFile(filename, DefaultWAVFormat, convertSamples[T, float64](data), nil)
+	}
+	dt, err := StringToDataType(ext(filename))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, nil, NoPredictor, func(w io.Writer) error {
+		return WriteAs(w, dt, data)
+	})
+}
+
+// convertSamples converts data to the sample type To without scaling.
+// data itself is returned if the types are the same.
+func convertSamples[From, To Sample](data []From) []To {
+	if ret, ok := any(data).([]To); ok {
+		return ret
+	}
+	return conv.Convert[From, To](data)
+}
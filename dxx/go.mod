@@ -1,3 +1,8 @@
 module github.com/tetsuzawa/go-soundlib/dxx
 
-go 1.15
+go 1.18
+
+require (
+	github.com/mjibson/go-dsp v0.0.0-20180508042940-11479a337f12
+	github.com/tetsuzawa/go-soundlib/conv v0.2.0
+)
@@ -0,0 +1,167 @@
+package dxx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// MetaExt is the extension of the metadata sidecar file.
+// The metadata of sound.DDB is stored in sound.DDB.json.
+const MetaExt = ".json"
+
+var (
+	ErrSamplingRateMismatch = errors.New("sampling rate mismatch")
+)
+
+// Meta is the metadata of a .DXX file.
+// DXX files have no header, so the metadata is stored in a JSON sidecar file next to the data file.
+// Zero values mean that the field is unknown.
+type Meta struct {
+	// SamplingRate is the sampling rate [Hz].
+	SamplingRate int `json:"sampling_rate,omitempty"`
+	// Channels is the number of channels.
+	Channels int `json:"channels,omitempty"`
+	// Units is the physical unit of the samples. e.g. "Pa"
+	Units string `json:"units,omitempty"`
+	// Creator is the name of the tool which created the file.
+	Creator string `json:"creator,omitempty"`
+	// CreatedAt is the time when the file was created.
+	// WriteMeta records the time of writing if it is zero.
+	CreatedAt time.Time `json:"created_at"`
+	// Params holds the parameters used to create the file.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// CheckSamplingRate returns ErrSamplingRateMismatch if the sampling rate of the metadata is known and differs from fs.
+// A nil Meta is treated as unknown.
+func (m *Meta) CheckSamplingRate(fs int) error {
+	if m == nil || m.SamplingRate == 0 || m.SamplingRate == fs {
+		return nil
+	}
+	return fmt.Errorf("%w: %d Hz, expected %d Hz", ErrSamplingRateMismatch, m.SamplingRate, fs)
+}
+
+// MetaName returns the filename of the metadata sidecar of the .DXX file.
+func MetaName(filename string) string {
+	return filename + MetaExt
+}
+
+// ReadMeta reads the metadata sidecar of the .DXX file.
+// If the sidecar does not exist, ReadMeta returns nil and no error.
+func ReadMeta(filename string) (*Meta, error) {
+	return ReadMetaFS(osFS{}, filename)
+}
+
+// ReadMetaFS reads the metadata sidecar of the .DXX file name in fsys.
+// If the sidecar does not exist, ReadMetaFS returns nil and no error.
+func ReadMetaFS(fsys fs.FS, name string) (*Meta, error) {
+	filename := MetaName(name)
+	b, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	m := &Meta{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return m, nil
+}
+
+// WriteMeta writes the metadata sidecar of the .DXX file.
+// A zero CreatedAt is written as the current time; m is not modified.
+func WriteMeta(filename string, m *Meta) error {
+	if m.CreatedAt.IsZero() {
+		c := *m
+		c.CreatedAt = time.Now()
+		m = &c
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(MetaName(filename), nil, NoPredictor, func(w io.Writer) error {
+		_, err := w.Write(append(b, '\n'))
+		return err
+	})
+}
+
+// ReadFileWithMeta reads .DXX file and its metadata sidecar.
+// If the sidecar does not exist, the returned Meta is nil.
+// For .wav files, the sampling rate and the channel count are taken from the WAV header.
+func ReadFileWithMeta(filename string) ([]float64, *Meta, error) {
+	return ReadFileWithMetaFS(osFS{}, filename)
+}
+
+// ReadFileWithMetaFS reads the .DXX file name in fsys and its metadata sidecar like ReadFileWithMeta.
+func ReadFileWithMetaFS(fsys fs.FS, name string) ([]float64, *Meta, error) {
+	return readFileWithMetaFS(fsys, name, nil)
+}
+
+// ReadFileWithMetaFSWithOptions reads the .DXX file name in fsys and its metadata sidecar like ReadFileWithMetaFS,
+// and converts the samples as specified by opts. The samples of .wav files are read as by ReadFileWithMetaFS.
+func ReadFileWithMetaFSWithOptions(fsys fs.FS, name string, opts *Options) ([]float64, *Meta, error) {
+	return readFileWithMetaFS(fsys, name, opts)
+}
+
+func readFileWithMetaFS(fsys fs.FS, name string, opts *Options) ([]float64, *Meta, error) {
+	if isWAV(name) {
+		return readWAVFileWithMeta(fsys, name)
+	}
+
+	data, err := ReadFromFSWithOptions(fsys, name, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := ReadMetaFS(fsys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, m, nil
+}
+
+// WriteFileWithMeta writes data to .DXX file and the metadata to its sidecar.
+// If m is nil, no sidecar is written.
+// For .wav files, the sampling rate and the channel count of m are also written to the WAV header.
+func WriteFileWithMeta(filename string, data []float64, m *Meta) error {
+	return writeFileWithMeta(filename, data, m, nil)
+}
+
+func writeFileWithMeta(filename string, data []float64, m *Meta, opts *Options) error {
+	var err error
+	if isWAV(filename) {
+		err = writeWAVFile(filename, wavFormatFromMeta(m), data, opts)
+	} else {
+		err = WriteToFileWithOptions(filename, data, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return nil
+	}
+	return WriteMeta(filename, m)
+}
+
+func readWAVFileWithMeta(fsys fs.FS, filename string) ([]float64, *Meta, error) {
+	data, f, err := readWAVFileFS(fsys, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := ReadMetaFS(fsys, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m == nil {
+		m = &Meta{}
+	}
+	m.SamplingRate = f.SampleRate
+	m.Channels = f.Channels
+	return data, m, nil
+}
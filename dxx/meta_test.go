@@ -0,0 +1,114 @@
+package dxx
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetaRoundTrip(t *testing.T) {
+	created := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	values := []float64{0.5, -0.25, 0, 0.75}
+	for _, c := range []struct {
+		name string
+		meta *Meta
+		// want is the metadata read back
+		want *Meta
+	}{
+		{
+			"sound.DDB",
+			&Meta{SamplingRate: 44100, Channels: 1, Units: "Pa", Creator: "test", CreatedAt: created, Params: map[string]string{"seed": "1"}},
+			&Meta{SamplingRate: 44100, Channels: 1, Units: "Pa", Creator: "test", CreatedAt: created, Params: map[string]string{"seed": "1"}},
+		},
+		// the sampling rate and the channel count of WAV files are those of the header
+		{
+			"sound.wav",
+			&Meta{SamplingRate: 8000, Units: "Pa", CreatedAt: created},
+			&Meta{SamplingRate: 8000, Channels: 1, Units: "Pa", CreatedAt: created},
+		},
+	} {
+		filename := filepath.Join(t.TempDir(), c.name)
+		if err := WriteFileWithMeta(filename, values, c.meta); err != nil {
+			t.Fatal(err)
+		}
+		data, m, err := ReadFileWithMeta(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSameFloat64s(t, data, values)
+		if !reflect.DeepEqual(m, c.want) {
+			t.Errorf("%s: read %+v, want %+v", c.name, m, c.want)
+		}
+	}
+}
+
+func TestWriteMetaCreatedAt(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "sound.DDB")
+	m := &Meta{SamplingRate: 48000}
+	before := time.Now()
+	if err := WriteMeta(filename, m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.CreatedAt.IsZero() {
+		t.Errorf("WriteMeta modified the Meta: %+v", m)
+	}
+	got, err := ReadMeta(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CreatedAt.Before(before.Truncate(time.Second)) || got.CreatedAt.After(time.Now()) {
+		t.Errorf("CreatedAt of the sidecar is %v, want the time of writing", got.CreatedAt)
+	}
+	// the sidecar has no zero time
+	b, err := os.ReadFile(MetaName(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := raw["created_at"].(string); s == "" || strings.HasPrefix(s, "0001-") {
+		t.Errorf("created_at is %q", s)
+	}
+}
+
+func TestReadMeta(t *testing.T) {
+	dir := t.TempDir()
+	if m, err := ReadMeta(filepath.Join(dir, "none.DDB")); m != nil || err != nil {
+		t.Errorf("ReadMeta without a sidecar = %+v, %v, want nil, nil", m, err)
+	}
+	bad := filepath.Join(dir, "bad.DDB")
+	if err := os.WriteFile(MetaName(bad), []byte(`{"sampling_rate": "48000"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMeta(bad); err == nil || !strings.HasPrefix(err.Error(), MetaName(bad)+":") {
+		t.Errorf("ReadMeta of a malformed sidecar: got %v, want an error of %s", err, MetaName(bad))
+	}
+}
+
+func TestCheckSamplingRate(t *testing.T) {
+	for _, c := range []struct {
+		meta *Meta
+		fs   int
+		err  bool
+	}{
+		{nil, 48000, false},
+		{&Meta{}, 48000, false},
+		{&Meta{SamplingRate: 48000}, 48000, false},
+		{&Meta{SamplingRate: 44100}, 48000, true},
+	} {
+		err := c.meta.CheckSamplingRate(c.fs)
+		if c.err != errors.Is(err, ErrSamplingRateMismatch) || c.err != (err != nil) {
+			t.Errorf("%+v.CheckSamplingRate(%d) = %v", c.meta, c.fs, err)
+		}
+	}
+	if err := (&Meta{SamplingRate: 44100}).CheckSamplingRate(48000); !strings.Contains(err.Error(), "44100 Hz, expected 48000 Hz") {
+		t.Errorf("the error %q does not tell the rates", err)
+	}
+}
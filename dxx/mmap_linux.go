@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package dxx
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"syscall"
+)
+
+// mmap maps the content of the file into memory.
+// Files larger than the address space, which is the case on 32-bit platforms, are rejected.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	if uint64(size) > math.MaxInt {
+		return nil, fmt.Errorf("%s: %d bytes are too large to map into memory", f.Name(), size)
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package dxx
+
+import "os"
+
+// mmap is not supported on this platform.
+// File falls back to reading the file with ReadAt.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return nil, nil
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,192 @@
+package dxx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrInvalidChannels = errors.New("invalid number of channels")
+	ErrChannelLength   = errors.New("channels have different lengths")
+)
+
+// Interleave interleaves the samples of the channels into a single slice.
+// e.g. [[L0 L1] [R0 R1]] -> [L0 R0 L1 R1]
+func Interleave(chs [][]float64) ([]float64, error) {
+	if len(chs) == 0 {
+		return nil, ErrInvalidChannels
+	}
+	frames := len(chs[0])
+	for _, ch := range chs {
+		if len(ch) != frames {
+			return nil, ErrChannelLength
+		}
+	}
+	data := make([]float64, frames*len(chs))
+	for c, ch := range chs {
+		for i, v := range ch {
+			data[i*len(chs)+c] = v
+		}
+	}
+	return data, nil
+}
+
+// Deinterleave splits interleaved samples into the specified number of channels.
+// e.g. [L0 R0 L1 R1] -> [[L0 L1] [R0 R1]]
+func Deinterleave(data []float64, channels int) ([][]float64, error) {
+	if channels <= 0 {
+		return nil, ErrInvalidChannels
+	}
+	if len(data)%channels != 0 {
+		return nil, ErrChannelLength
+	}
+	frames := len(data) / channels
+	chs := make([][]float64, channels)
+	for c := range chs {
+		chs[c] = make([]float64, frames)
+		for i := range chs[c] {
+			chs[c][i] = data[i*channels+c]
+		}
+	}
+	return chs, nil
+}
+
+// ReadChannels reads interleaved multichannel data from reader as specified data type.
+// frames is the number of samples per channel.
+func ReadChannels(r io.Reader, dt DataType, channels, frames int) ([][]float64, error) {
+	if channels <= 0 {
+		return nil, ErrInvalidChannels
+	}
+	data, err := Read(r, dt, channels*frames)
+	if err != nil {
+		return nil, err
+	}
+	return Deinterleave(data, channels)
+}
+
+// WriteChannels writes multichannel data to writer as specified data type.
+// The samples are interleaved frame by frame.
+func WriteChannels(w io.Writer, dt DataType, chs [][]float64) error {
+	data, err := Interleave(chs)
+	if err != nil {
+		return err
+	}
+	return Write(w, dt, data)
+}
+
+// ReadChannelsFromFile reads interleaved multichannel .DXX file.
+// The number of channels is taken from the metadata sidecar.
+// A file without sidecar or channel count is read as mono.
+func ReadChannelsFromFile(filename string) ([][]float64, *Meta, error) {
+	return readChannelsFromFile(filename, nil)
+}
+
+func readChannelsFromFile(filename string, opts *Options) ([][]float64, *Meta, error) {
+	data, m, err := readFileWithMetaFS(osFS{}, filename, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	channels := 1
+	if m != nil && m.Channels != 0 {
+		channels = m.Channels
+	}
+	chs, err := Deinterleave(data, channels)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chs, m, nil
+}
+
+// WriteChannelsToFile writes multichannel data to .DXX file.
+// The channel count is recorded in the metadata sidecar together with the other fields of m.
+// m may be nil.
+func WriteChannelsToFile(filename string, chs [][]float64, m *Meta) error {
+	return writeChannelsToFile(filename, chs, m, nil)
+}
+
+func writeChannelsToFile(filename string, chs [][]float64, m *Meta, opts *Options) error {
+	data, err := Interleave(chs)
+	if err != nil {
+		return err
+	}
+	var meta Meta
+	if m != nil {
+		meta = *m
+	} else {
+		meta.CreatedAt = time.Now()
+	}
+	meta.Channels = len(chs)
+	return writeFileWithMeta(filename, data, &meta, opts)
+}
+
+// MergeFiles merges mono .DXX files into a multichannel .DXX file.
+// e.g. MergeFiles("out.DDB", "out_L.DDB", "out_R.DDB")
+// The metadata of the first source which has a sidecar is propagated to dst.
+// The sources whose sampling rates are known must have the same rate,
+// otherwise MergeFiles returns ErrSamplingRateMismatch.
+// The samples are merged as stored, so the levels of the sources are kept.
+func MergeFiles(dst string, srcs ...string) error {
+	if len(srcs) == 0 {
+		return ErrInvalidChannels
+	}
+	chs := make([][]float64, len(srcs))
+	var meta *Meta
+	samplingRate := 0
+	for i, src := range srcs {
+		data, m, err := readFileWithMetaFS(osFS{}, src, preserveOptions())
+		if err != nil {
+			return err
+		}
+		if m != nil && m.Channels > 1 {
+			return fmt.Errorf("%s: %w", src, ErrInvalidChannels)
+		}
+		if samplingRate != 0 {
+			if err := m.CheckSamplingRate(samplingRate); err != nil {
+				return fmt.Errorf("%s: %w", src, err)
+			}
+		} else if m != nil {
+			samplingRate = m.SamplingRate
+		}
+		if meta == nil {
+			meta = m
+		}
+		chs[i] = data
+	}
+	if meta != nil && meta.SamplingRate == 0 {
+		// the first sidecar does not know the rate which another one records
+		m := *meta
+		m.SamplingRate = samplingRate
+		meta = &m
+	}
+	return writeChannelsToFile(dst, chs, meta, preserveOptions())
+}
+
+// SplitFile splits a multichannel .DXX file into mono .DXX files.
+// The number of dsts must equal the channel count of src.
+// e.g. SplitFile("out.DDB", "out_L.DDB", "out_R.DDB")
+// The samples are split as stored.
+func SplitFile(src string, dsts ...string) error {
+	chs, m, err := readChannelsFromFile(src, preserveOptions())
+	if err != nil {
+		return err
+	}
+	if len(chs) != len(dsts) {
+		return ErrInvalidChannels
+	}
+	for i, dst := range dsts {
+		if m == nil {
+			if err := writeFileWithMeta(dst, chs[i], nil, preserveOptions()); err != nil {
+				return err
+			}
+			continue
+		}
+		meta := *m
+		meta.Channels = 1
+		if err := writeFileWithMeta(dst, chs[i], &meta, preserveOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
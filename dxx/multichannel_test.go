@@ -0,0 +1,94 @@
+package dxx
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+func TestMergeFilesSamplingRate(t *testing.T) {
+	dir := t.TempDir()
+	src := func(name string, samplingRate int) string {
+		filename := filepath.Join(dir, name)
+		var m *Meta
+		if samplingRate != 0 {
+			m = &Meta{SamplingRate: samplingRate}
+		}
+		if err := WriteFileWithMeta(filename, []float64{1, 2, 3}, m); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	unknown := src("unknown.DDB", 0)
+	l48 := src("l48.DDB", 48000)
+	r48 := src("r48.DDB", 48000)
+	r44 := src("r44.DDB", 44100)
+	dst := filepath.Join(dir, "out.DDB")
+
+	if err := MergeFiles(dst, l48, r44); !errors.Is(err, ErrSamplingRateMismatch) {
+		t.Errorf("MergeFiles of 48000 Hz and 44100 Hz: got %v, want ErrSamplingRateMismatch", err)
+	}
+	if err := MergeFiles(dst, unknown, l48, r44); !errors.Is(err, ErrSamplingRateMismatch) {
+		t.Errorf("MergeFiles of unknown, 48000 Hz and 44100 Hz: got %v, want ErrSamplingRateMismatch", err)
+	}
+
+	for _, srcs := range [][]string{{l48, r48}, {unknown, r48}, {l48, unknown}} {
+		if err := MergeFiles(dst, srcs...); err != nil {
+			t.Fatalf("MergeFiles(%v): %v", srcs, err)
+		}
+		_, m, err := ReadChannelsFromFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m == nil || m.SamplingRate != 48000 || m.Channels != 2 {
+			t.Errorf("MergeFiles(%v): metadata %+v, want 2 channels at 48000 Hz", srcs, m)
+		}
+	}
+}
+
+// TestMergeSplitKeepLevels checks that the channels are not normalised each on its own.
+func TestMergeSplitKeepLevels(t *testing.T) {
+	dir := t.TempDir()
+	l, r := filepath.Join(dir, "l.DSB"), filepath.Join(dir, "r.DSB")
+	preserve := &Options{Scaler: &conv.Scaler{}}
+	if err := WriteToFileWithOptions(l, []float64{100, -200}, preserve); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteToFileWithOptions(r, []float64{10, 20}, preserve); err != nil {
+		t.Fatal(err)
+	}
+
+	merged := filepath.Join(dir, "lr.DSB")
+	if err := MergeFiles(merged, l, r); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadInt16s(bytes.NewReader(b), DSB, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{100, 10, -200, 20}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("merged %v, want %v", got, want)
+	}
+
+	split := filepath.Join(dir, "r2.DSB")
+	if err := SplitFile(merged, filepath.Join(dir, "l2.DSB"), split); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = os.ReadFile(split); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = ReadInt16s(bytes.NewReader(b), DSB, -1); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != 10 || got[1] != 20 {
+		t.Errorf("split %v, want [10 20]", got)
+	}
+}
@@ -0,0 +1,150 @@
+package dxx
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+// Options controls the conversion of the samples in ReadWithOptions and WriteWithOptions,
+// and how WriteToFileWithOptions writes files.
+// A nil *Options is valid and uses the default of each field.
+type Options struct {
+	// Scaler converts the samples between the stored data type and float64.
+	// The number of saturated samples is accumulated in Scaler.Clipped.
+	// The default, a nil Scaler, is the normalisation of Read and Write:
+	// DSX and DFX samples are normalised by their peak absolute value to an amplitude of 10000 when read,
+	// and to 32767 for DSX and 10000 for DFX when written. DDX samples are kept.
+	// Set a conv.Scaler in conv.Preserve mode, e.g. &conv.Scaler{}, to keep the stored values
+	// so that reading and writing is bit-exact and the levels of different files are comparable.
+	Scaler *conv.Scaler
+	// ByteOrder is the byte order of binary data. The default is binary.LittleEndian.
+	// Use DetectByteOrder to guess the byte order of data of unknown origin.
+	ByteOrder binary.ByteOrder
+	// NoOverwrite makes WriteToFileWithOptions fail with an error satisfying errors.Is(err, fs.ErrExist)
+	// instead of replacing an existing file.
+	NoOverwrite bool
+	// Predictor is the predictor of binary data in compressed files written by WriteToFileWithOptions.
+	// The default is NoPredictor. The files record the predictor and the byte order of big-endian data,
+	// so predicted data is read without this option and in the byte order it was written in, whatever ByteOrder is.
+	Predictor Predictor
+	// Format is the format verb of DFA and DDA values as accepted by strconv.FormatFloat: 'e', 'E', 'f', 'g' or 'G'.
+	// The default is 'g'.
+	Format byte
+	// Precision is the number of digits of DFA and DDA values as interpreted by strconv.FormatFloat for Format.
+	// The default 0 writes the fewest digits which read back to the same value, so that ASCII data round-trips exactly.
+	// Any other precision may lose precision.
+	Precision int
+}
+
+func (o *Options) byteOrder() binary.ByteOrder {
+	if o == nil || o.ByteOrder == nil {
+		return binary.LittleEndian
+	}
+	return o.ByteOrder
+}
+
+func (o *Options) predictor() Predictor {
+	if o == nil {
+		return NoPredictor
+	}
+	return o.Predictor
+}
+
+// predictorFor returns the predictor to write the file of the data type with.
+// The predictor is used only for binary data in compressed files.
+func (o *Options) predictorFor(filename string, dt DataType) (Predictor, error) {
+	p := o.predictor()
+	if p < NoPredictor || p > XOR {
+		return NoPredictor, ErrUnknownPredictor
+	}
+	if c, _ := CompressionOf(filename); c == NoCompression || !dt.IsBinary() {
+		return NoPredictor, nil
+	}
+	return p, nil
+}
+
+func (o *Options) asciiFormat() (asciiFormat, error) {
+	if o == nil {
+		return shortestFormat, nil
+	}
+	return newASCIIFormat(o.Format, o.Precision)
+}
+
+// preserveOptions returns the options which keep the stored values.
+func preserveOptions() *Options {
+	return &Options{Scaler: &conv.Scaler{}}
+}
+
+// scaler returns the Scaler of the options, or nil for the default normalisation.
+func (o *Options) scaler() *conv.Scaler {
+	if o == nil {
+		return nil
+	}
+	return o.Scaler
+}
+
+// ReadWithOptions reads data from reader as specified data type and converts the samples as specified by opts.
+func ReadWithOptions(r io.Reader, dt DataType, length int, opts *Options) ([]float64, error) {
+	switch dt {
+	case DSA, DSB:
+		i16s, err := readInt16s(r, dt, length, opts.byteOrder())
+		if err != nil {
+			return nil, err
+		}
+		if s := opts.scaler(); s != nil {
+			return s.Int16sToFloat64s(i16s), nil
+		}
+		return conv.Int16sToFloat64s(i16s), nil
+	case DFA, DFB:
+		f32s, err := readFloat32s(r, dt, length, opts.byteOrder())
+		if err != nil {
+			return nil, err
+		}
+		if s := opts.scaler(); s != nil {
+			return s.Float32sToFloat64s(f32s), nil
+		}
+		return conv.Float32sToFloat64s(f32s), nil
+	case DDA, DDB:
+		f64s, err := readFloat64s(r, dt, length, opts.byteOrder())
+		if err != nil {
+			return nil, err
+		}
+		if s := opts.scaler(); s != nil && s.Mode != conv.Preserve {
+			return s.Float64s(f64s), nil
+		}
+		return f64s, nil
+	default:
+		return nil, ErrUnknownDataType
+	}
+}
+
+// WriteWithOptions converts data as specified by opts and writes it to writer as specified data type.
+func WriteWithOptions(w io.Writer, dt DataType, data []float64, opts *Options) error {
+	af, err := opts.asciiFormat()
+	if err != nil {
+		return err
+	}
+	switch dt {
+	case DSA, DSB:
+		i16s := conv.Float64sToInt16s
+		if s := opts.scaler(); s != nil {
+			i16s = s.Float64sToInt16s
+		}
+		return writeInt16s(w, dt, i16s(data), opts.byteOrder())
+	case DFA, DFB:
+		f32s := conv.Float64sToFloat32s
+		if s := opts.scaler(); s != nil {
+			f32s = s.Float64sToFloat32s
+		}
+		return writeFloat32s(w, dt, f32s(data), opts.byteOrder(), af)
+	case DDA, DDB:
+		if s := opts.scaler(); s != nil && s.Mode != conv.Preserve {
+			data = s.Float64s(data)
+		}
+		return writeFloat64s(w, dt, data, opts.byteOrder(), af)
+	default:
+		return ErrUnknownDataType
+	}
+}
@@ -0,0 +1,202 @@
+package dxx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+)
+
+// TestDefaultScaling pins the normalisation of Read and Write, which nil Options keep.
+func TestDefaultScaling(t *testing.T) {
+	data := []float64{0, 0.25, -0.5, 1}
+	for _, dt := range []DataType{DSA, DSB} {
+		var buf bytes.Buffer
+		if err := Write(&buf, dt, data); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := ReadInt16s(bytes.NewReader(buf.Bytes()), dt, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range []int16{0, 8191, -16383, 32767} {
+			if stored[i] != want {
+				t.Errorf("%v: Write stored %v, want [0 8191 -16383 32767]", dt, stored)
+				break
+			}
+		}
+
+		got, err := Read(bytes.NewReader(buf.Bytes()), dt, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[3] != 10000 || got[2] >= 0 {
+			t.Errorf("%v: Read %v, want the peak at 10000 and the signs kept", dt, got)
+		}
+	}
+
+	for _, dt := range []DataType{DFA, DFB} {
+		var buf bytes.Buffer
+		if err := Write(&buf, dt, data); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := ReadFloat32s(bytes.NewReader(buf.Bytes()), dt, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range []float32{0, 2500, -5000, 10000} {
+			if stored[i] != want {
+				t.Errorf("%v: Write stored %v, want [0 2500 -5000 10000]", dt, stored)
+				break
+			}
+		}
+	}
+
+	for _, dt := range []DataType{DDA, DDB} {
+		var buf bytes.Buffer
+		if err := Write(&buf, dt, data); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Read(&buf, dt, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range data {
+			if got[i] != data[i] {
+				t.Errorf("%v: read %v, want %v", dt, got, data)
+				break
+			}
+		}
+	}
+}
+
+func TestPreserveScaling(t *testing.T) {
+	data := []float64{0, 1, -1, 1000, -32768, 32767}
+	for _, dt := range dataTypes {
+		var buf bytes.Buffer
+		if err := WriteWithOptions(&buf, dt, data, &Options{Scaler: &conv.Scaler{}}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadWithOptions(&buf, dt, -1, &Options{Scaler: &conv.Scaler{Mode: conv.Preserve}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range data {
+			if got[i] != data[i] {
+				t.Errorf("%v: read %v, want %v", dt, got, data)
+				break
+			}
+		}
+	}
+
+	s := &conv.Scaler{Mode: conv.FullScale}
+	var buf bytes.Buffer
+	if err := WriteWithOptions(&buf, DSB, []float64{0.5, -1, 2}, &Options{Scaler: s}); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := ReadInt16s(&buf, DSB, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored[0] != 16384 || stored[1] != math.MinInt16 || stored[2] != math.MaxInt16 || s.Clipped != 1 {
+		t.Errorf("FullScale stored %v with %d clipped, want [16384 -32768 32767] with 1 clipped", stored, s.Clipped)
+	}
+}
+
+// encodeOrder returns values stored as the binary data type in the byte order, encoded sample by sample.
+func encodeOrder(dt DataType, values []float64, order binary.ByteOrder) []byte {
+	b := make([]byte, len(values)*dt.ByteLen())
+	for i, v := range values {
+		s := b[i*dt.ByteLen():]
+		switch dt {
+		case DSB:
+			order.PutUint16(s, uint16(int16(v)))
+		case DFB:
+			order.PutUint32(s, math.Float32bits(float32(v)))
+		case DDB:
+			order.PutUint64(s, math.Float64bits(v))
+		}
+	}
+	return b
+}
+
+func TestByteOrderRoundTrip(t *testing.T) {
+	values := []float64{1, -2, 256, -32768, 32767, 0}
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		opts := &Options{Scaler: &conv.Scaler{}, ByteOrder: order}
+		for _, dt := range []DataType{DSB, DFB, DDB} {
+			want := encodeOrder(dt, values, order)
+
+			var buf bytes.Buffer
+			if err := WriteWithOptions(&buf, dt, values, opts); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("%v %v: WriteWithOptions wrote % x, want % x", dt, order, buf.Bytes(), want)
+			}
+			got, err := ReadWithOptions(bytes.NewReader(want), dt, -1, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameFloat64s(t, got, values)
+
+			filename := filepath.Join(t.TempDir(), "signal."+dt.String())
+			if err := WriteToFileWithOptions(filename, values, opts); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := os.ReadFile(filename); err != nil || !bytes.Equal(b, want) {
+				t.Fatalf("%v %v: WriteToFileWithOptions wrote % x, %v, want % x", dt, order, b, err, want)
+			}
+			if got, err = ReadFromFileWithOptions(filename, opts); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFloat64s(t, got, values)
+
+			f, err := Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.SetByteOrder(order)
+			got, err = f.Slice(0, f.Len())
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameFloat64s(t, got, values)
+
+			buf.Reset()
+			w := NewWriter(&buf, dt)
+			w.SetByteOrder(order)
+			if _, err := w.WriteSamples(values); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("%v %v: Writer wrote % x, want % x", dt, order, buf.Bytes(), want)
+			}
+			r := NewReader(bytes.NewReader(want), dt)
+			r.SetByteOrder(order)
+			got = make([]float64, len(values))
+			if n, err := r.ReadSamples(got); n != len(values) || err != nil {
+				t.Fatalf("%v %v: Reader read %d samples, %v", dt, order, n, err)
+			}
+			assertSameFloat64s(t, got, values)
+		}
+	}
+
+	// the byte order is not guessed: big-endian data read as little-endian has other values
+	be := encodeOrder(DSB, values, binary.BigEndian)
+	got, err := ReadWithOptions(bytes.NewReader(be), DSB, -1, &Options{Scaler: &conv.Scaler{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != 256 || got[2] != 1 {
+		t.Errorf("big-endian data read as little-endian: %v", got)
+	}
+}
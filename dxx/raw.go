@@ -0,0 +1,189 @@
+package dxx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var (
+	ErrDataTypeMismatch = errors.New("data type does not match the sample type")
+	ErrInvalidFormat    = errors.New("invalid ASCII format verb")
+)
+
+// ReadInt16s reads DSA or DSB data from reader as stored, without any scaling.
+// Binary data is in little-endian byte order.
+func ReadInt16s(r io.Reader, dt DataType, length int) ([]int16, error) {
+	return readInt16s(r, dt, length, binary.LittleEndian)
+}
+
+func readInt16s(r io.Reader, dt DataType, length int, order binary.ByteOrder) ([]int16, error) {
+	switch dt {
+	case DSA:
+		return readDSA(r, length)
+	case DSB:
+		return readDSB(r, length, order)
+	default:
+		return nil, ErrDataTypeMismatch
+	}
+}
+
+// ReadFloat32s reads DFA or DFB data from reader as stored, without any scaling.
+// Binary data is in little-endian byte order.
+func ReadFloat32s(r io.Reader, dt DataType, length int) ([]float32, error) {
+	return readFloat32s(r, dt, length, binary.LittleEndian)
+}
+
+func readFloat32s(r io.Reader, dt DataType, length int, order binary.ByteOrder) ([]float32, error) {
+	switch dt {
+	case DFA:
+		return readDFA(r, length)
+	case DFB:
+		return readDFB(r, length, order)
+	default:
+		return nil, ErrDataTypeMismatch
+	}
+}
+
+// ReadFloat64s reads DDA or DDB data from reader as stored.
+// Binary data is in little-endian byte order.
+func ReadFloat64s(r io.Reader, dt DataType, length int) ([]float64, error) {
+	return readFloat64s(r, dt, length, binary.LittleEndian)
+}
+
+func readFloat64s(r io.Reader, dt DataType, length int, order binary.ByteOrder) ([]float64, error) {
+	switch dt {
+	case DDA:
+		return readDDA(r, length)
+	case DDB:
+		return readDDB(r, length, order)
+	default:
+		return nil, ErrDataTypeMismatch
+	}
+}
+
+// WriteInt16s writes data to writer as DSA or DSB, without any scaling.
+// Binary data is in little-endian byte order.
+func WriteInt16s(w io.Writer, dt DataType, data []int16) error {
+	return writeInt16s(w, dt, data, binary.LittleEndian)
+}
+
+func writeInt16s(w io.Writer, dt DataType, data []int16, order binary.ByteOrder) error {
+	buf := bufio.NewWriter(w)
+	var err error
+	switch dt {
+	case DSA:
+		err = writeDSA(buf, data)
+	case DSB:
+		err = writeDSB(buf, data, order)
+	default:
+		err = ErrDataTypeMismatch
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// WriteFloat32s writes data to writer as DFA or DFB, without any scaling.
+// DFA values are written in the shortest form which reads back to the same float32.
+// Binary data is in little-endian byte order.
+func WriteFloat32s(w io.Writer, dt DataType, data []float32) error {
+	return writeFloat32s(w, dt, data, binary.LittleEndian, shortestFormat)
+}
+
+func writeFloat32s(w io.Writer, dt DataType, data []float32, order binary.ByteOrder, af asciiFormat) error {
+	buf := bufio.NewWriter(w)
+	var err error
+	switch dt {
+	case DFA:
+		err = writeDFA(buf, data, af)
+	case DFB:
+		err = writeDFB(buf, data, order)
+	default:
+		err = ErrDataTypeMismatch
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// WriteFloat64s writes data to writer as DDA or DDB.
+// DDA values are written in the shortest form which reads back to the same float64.
+// Binary data is in little-endian byte order.
+func WriteFloat64s(w io.Writer, dt DataType, data []float64) error {
+	return writeFloat64s(w, dt, data, binary.LittleEndian, shortestFormat)
+}
+
+func writeFloat64s(w io.Writer, dt DataType, data []float64, order binary.ByteOrder, af asciiFormat) error {
+	buf := bufio.NewWriter(w)
+	var err error
+	switch dt {
+	case DDA:
+		err = writeDDA(buf, data, af)
+	case DDB:
+		err = writeDDB(buf, data, order)
+	default:
+		err = ErrDataTypeMismatch
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// asciiFormat is the format of DFA and DDA values, as the fmt and prec arguments of strconv.FormatFloat.
+type asciiFormat struct {
+	verb byte
+	prec int
+}
+
+// shortestFormat writes the fewest digits which read back to the same value.
+var shortestFormat = asciiFormat{'g', -1}
+
+// newASCIIFormat returns the format of the verb and the precision. A precision of 0 means the shortest form.
+func newASCIIFormat(verb byte, prec int) (asciiFormat, error) {
+	switch verb {
+	case 0:
+		verb = 'g'
+	case 'e', 'E', 'f', 'g', 'G':
+	default:
+		return asciiFormat{}, fmt.Errorf("%w: %q", ErrInvalidFormat, verb)
+	}
+	if prec <= 0 {
+		prec = -1
+	}
+	return asciiFormat{verb, prec}, nil
+}
+
+// append appends v formatted as a value of bitSize bits and a newline to b.
+func (af asciiFormat) append(b []byte, v float64, bitSize int) []byte {
+	b = strconv.AppendFloat(b, v, af.verb, af.prec, bitSize)
+	return append(b, '\n')
+}
+
+func writeDFA(w io.Writer, data []float32, af asciiFormat) error {
+	var b []byte
+	for _, v := range data {
+		b = af.append(b[:0], float64(v), 32)
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDDA(w io.Writer, data []float64, af asciiFormat) error {
+	var b []byte
+	for _, v := range data {
+		b = af.append(b[:0], v, 64)
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package dxx
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// float64Values are the values whose DDA must read back bit-exactly.
+func float64Values() []float64 {
+	values := []float64{
+		0, math.Copysign(0, -1), 1, -1, 0.1, 1.0 / 3,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+		math.MaxFloat64, -math.MaxFloat64,
+		math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64,
+		// subnormals
+		math.Float64frombits(0x000FFFFFFFFFFFFF), math.Float64frombits(0x0000000000001234),
+		math.Float64frombits(0x8008000000000000),
+		// the smallest normal
+		math.Float64frombits(0x0010000000000000),
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		values = append(values, rng.NormFloat64()*math.Pow(10, float64(rng.Intn(40)-20)))
+		if v := math.Float64frombits(rng.Uint64()); !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// float32Values are the values whose DFA must read back bit-exactly.
+func float32Values() []float32 {
+	values := []float32{
+		0, float32(math.Copysign(0, -1)), 1, -1, 0.1, 1.0 / 3,
+		float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.NaN()),
+		math.MaxFloat32, -math.MaxFloat32,
+		math.SmallestNonzeroFloat32, -math.SmallestNonzeroFloat32,
+		// subnormals
+		math.Float32frombits(0x007FFFFF), math.Float32frombits(0x00001234), math.Float32frombits(0x80400000),
+		// the smallest normal
+		math.Float32frombits(0x00800000),
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		values = append(values, float32(rng.NormFloat64()*math.Pow(10, float64(rng.Intn(20)-10))))
+		if v := math.Float32frombits(rng.Uint32()); !math.IsNaN(float64(v)) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func assertSameFloat64s(t *testing.T, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Float64bits(got[i]) != math.Float64bits(want[i]) {
+			t.Fatalf("value %d: got %v (%#016x), want %v (%#016x)", i, got[i], math.Float64bits(got[i]), want[i], math.Float64bits(want[i]))
+		}
+	}
+}
+
+func assertSameFloat32s(t *testing.T, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("read %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Float32bits(got[i]) != math.Float32bits(want[i]) {
+			t.Fatalf("value %d: got %v (%#08x), want %v (%#08x)", i, got[i], math.Float32bits(got[i]), want[i], math.Float32bits(want[i]))
+		}
+	}
+}
+
+func TestDDARoundTrip(t *testing.T) {
+	values := float64Values()
+	for _, verb := range []byte{0, 'e', 'E', 'f', 'g', 'G'} {
+		name := string(rune(verb))
+		if verb == 0 {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteWithOptions(&buf, DDA, values, &Options{Format: verb}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFloat64s(&buf, DDA, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameFloat64s(t, got, values)
+		})
+	}
+}
+
+func TestDFARoundTrip(t *testing.T) {
+	values := float32Values()
+	var buf bytes.Buffer
+	if err := WriteFloat32s(&buf, DFA, values); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFloat32s(&buf, DFA, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameFloat32s(t, got, values)
+}
+
+func TestStreamASCIIRoundTrip(t *testing.T) {
+	f32s := float32Values()
+	f32Values := make([]float64, len(f32s))
+	for i, v := range f32s {
+		f32Values[i] = float64(v)
+	}
+	for _, c := range []struct {
+		dt     DataType
+		values []float64
+	}{
+		{DDA, float64Values()},
+		{DFA, f32Values},
+	} {
+		t.Run(c.dt.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, c.dt)
+			if err := w.SetFormat('e', 0); err != nil {
+				t.Fatal(err)
+			}
+			// in several calls, to cross the buffer boundaries.
+			for i := 0; i < len(c.values); i += 100 {
+				end := i + 100
+				if end > len(c.values) {
+					end = len(c.values)
+				}
+				if _, err := w.WriteSamples(c.values[i:end]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			r := NewReader(&buf, c.dt)
+			var got []float64
+			chunk := make([]float64, 77)
+			for {
+				n, err := r.ReadSamples(chunk)
+				got = append(got, chunk[:n]...)
+				if err != nil {
+					break
+				}
+			}
+			assertSameFloat64s(t, got, c.values)
+		})
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	for _, verb := range []byte{'b', 'x', 'v', 'd'} {
+		err := WriteWithOptions(&bytes.Buffer{}, DDA, []float64{1}, &Options{Format: verb})
+		if !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("Format %q: got %v, want ErrInvalidFormat", verb, err)
+		}
+		if err := NewWriter(&bytes.Buffer{}, DFA).SetFormat(verb, 0); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("SetFormat(%q): got %v, want ErrInvalidFormat", verb, err)
+		}
+	}
+}
+
+func TestPrecisionLosesDigits(t *testing.T) {
+	values := []float64{math.Pi, 1.0 / 3}
+	var buf bytes.Buffer
+	if err := WriteWithOptions(&buf, DDA, values, &Options{Format: 'f', Precision: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "3.142\n0.333\n"; got != want {
+		t.Errorf("written %q, want %q", got, want)
+	}
+	got, err := ReadFloat64s(strings.NewReader(buf.String()), DDA, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{3.142, 0.333} {
+		if got[i] != want {
+			t.Errorf("value %d: got %v, want %v", i, got[i], want)
+		}
+		if got[i] == values[i] {
+			t.Errorf("value %d: %v read back exactly with Precision 3", i, values[i])
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package dxx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Reader reads samples from a DXX stream block by block.
+// Unlike Read, Reader does not rescale the samples.
+// The values are returned as stored in the stream.
+type Reader struct {
+	dt    DataType
+	order binary.ByteOrder
+	br    *bufio.Reader
+	sc    *asciiScanner
+	buf   []byte
+	err   error
+}
+
+// NewReader returns a new Reader which reads samples of the specified data type from r.
+func NewReader(r io.Reader, dt DataType) *Reader {
+	rd := &Reader{dt: dt, order: binary.LittleEndian}
+	switch dt {
+	case DSA, DFA, DDA:
+		rd.sc = newASCIIScanner(r, dt)
+	case DSB, DFB, DDB:
+		rd.br = bufio.NewReader(r)
+		rd.buf = make([]byte, chunkSamples*dt.ByteLen())
+	default:
+		rd.err = ErrUnknownDataType
+	}
+	return rd
+}
+
+// DataType returns the data type of the stream.
+func (r *Reader) DataType() DataType {
+	return r.dt
+}
+
+// SetByteOrder sets the byte order of binary data. The default is binary.LittleEndian.
+// It must be called before the first ReadSamples.
+func (r *Reader) SetByteOrder(order binary.ByteOrder) {
+	r.order = order
+}
+
+// ReadSamples reads up to len(buf) samples into buf.
+// It returns the number of samples read and any error encountered.
+// At the end of the stream, ReadSamples returns 0, io.EOF.
+// A malformed ASCII line is reported as *ParseError.
+// If the stream ends in the middle of a binary sample, ReadSamples returns ErrTruncated.
+func (r *Reader) ReadSamples(buf []float64) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	var (
+		n   int
+		err error
+	)
+	if r.br != nil {
+		n, err = r.readBinary(buf)
+	} else {
+		n, err = r.readASCII(buf)
+	}
+	if err != nil {
+		r.err = err
+		if n > 0 && err == io.EOF {
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+func (r *Reader) readASCII(buf []float64) (int, error) {
+	for n := range buf {
+		v, err := r.sc.next()
+		if err != nil {
+			return n, err
+		}
+		buf[n] = v
+	}
+	return len(buf), nil
+}
+
+// readBinary reads and decodes the samples chunk by chunk.
+func (r *Reader) readBinary(buf []float64) (int, error) {
+	bl := r.dt.ByteLen()
+	n := 0
+	for n < len(buf) {
+		k := len(buf) - n
+		if k > chunkSamples {
+			k = chunkSamples
+		}
+		m, err := io.ReadFull(r.br, r.buf[:k*bl])
+		decodeSamples(buf[n:n+m/bl], r.buf[:m-m%bl], r.dt, r.order)
+		n += m / bl
+		switch {
+		case err == io.ErrUnexpectedEOF && m%bl == 0:
+			return n, io.EOF
+		case err == io.ErrUnexpectedEOF:
+			return n, ErrTruncated
+		case err != nil:
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Writer writes samples to a DXX stream block by block.
+// Unlike Write, Writer does not rescale the samples.
+// The values are stored as they are, rounded and saturated for DSA and DSB.
+// ASCII values are written in the shortest form which reads back to the same value, unless SetFormat is called.
+// Writer is buffered. Call Flush after the last sample has been written.
+type Writer struct {
+	dt    DataType
+	order binary.ByteOrder
+	af    asciiFormat
+	bw    *bufio.Writer
+	buf   []byte
+	err   error
+}
+
+// NewWriter returns a new Writer which writes samples of the specified data type to w.
+func NewWriter(w io.Writer, dt DataType) *Writer {
+	wr := &Writer{dt: dt, order: binary.LittleEndian, af: shortestFormat, bw: bufio.NewWriter(w)}
+	if dt.ByteLen() < 0 {
+		wr.err = ErrUnknownDataType
+		return wr
+	}
+	wr.buf = make([]byte, chunkSamples*dt.ByteLen())
+	return wr
+}
+
+// SetByteOrder sets the byte order of binary data. The default is binary.LittleEndian.
+// It must be called before the first WriteSamples.
+func (w *Writer) SetByteOrder(order binary.ByteOrder) {
+	w.order = order
+}
+
+// SetFormat sets the format verb and the precision of DFA and DDA values as Options.Format and Options.Precision.
+// It must be called before the first WriteSamples.
+func (w *Writer) SetFormat(verb byte, prec int) error {
+	af, err := newASCIIFormat(verb, prec)
+	if err != nil {
+		return err
+	}
+	w.af = af
+	return nil
+}
+
+// DataType returns the data type of the stream.
+func (w *Writer) DataType() DataType {
+	return w.dt
+}
+
+// WriteSamples writes data to the stream.
+// It returns the number of samples written and any error encountered.
+func (w *Writer) WriteSamples(data []float64) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var (
+		n   int
+		err error
+	)
+	if w.dt.IsBinary() {
+		n, err = w.writeBinary(data)
+	} else {
+		n, err = w.writeASCII(data)
+	}
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *Writer) writeASCII(data []float64) (int, error) {
+	for n, v := range data {
+		var err error
+		switch w.dt {
+		case DSA:
+			_, err = w.bw.WriteString(strconv.FormatInt(int64(saturateInt16(v)), 10) + "\n")
+		case DFA:
+			w.buf = w.af.append(w.buf[:0], float64(float32(v)), 32)
+			_, err = w.bw.Write(w.buf)
+		default:
+			w.buf = w.af.append(w.buf[:0], v, 64)
+			_, err = w.bw.Write(w.buf)
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(data), nil
+}
+
+// writeBinary encodes and writes the samples chunk by chunk.
+func (w *Writer) writeBinary(data []float64) (int, error) {
+	bl := w.dt.ByteLen()
+	n := 0
+	for n < len(data) {
+		k := len(data) - n
+		if k > chunkSamples {
+			k = chunkSamples
+		}
+		encodeSamples(w.buf, data[n:n+k], w.dt, w.order)
+		if _, err := w.bw.Write(w.buf[:k*bl]); err != nil {
+			return n, err
+		}
+		n += k
+	}
+	return n, nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.bw.Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+// saturateInt16 rounds v to the nearest integer and clips it to the range of int16.
+func saturateInt16(v float64) int16 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
@@ -0,0 +1,126 @@
+package dxx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// streamValues returns n integer values which every data type stores exactly.
+func streamValues(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i%2001 - 1000)
+	}
+	return values
+}
+
+// readAllSamples reads r to the end by chunks of the size and returns the samples and the error which ended the reading.
+func readAllSamples(r *Reader, size int) ([]float64, error) {
+	var got []float64
+	chunk := make([]float64, size)
+	for {
+		n, err := r.ReadSamples(chunk)
+		got = append(got, chunk[:n]...)
+		if err != nil {
+			return got, err
+		}
+	}
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	// more than chunkSamples, to cross the chunk boundaries of the binary codecs.
+	values := streamValues(chunkSamples + 3)
+	for _, dt := range dataTypes {
+		for _, size := range []int{1, 100, len(values), 2 * chunkSamples} {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, dt)
+			for i := 0; i < len(values); i += 1000 {
+				end := i + 1000
+				if end > len(values) {
+					end = len(values)
+				}
+				if n, err := w.WriteSamples(values[i:end]); err != nil || n != end-i {
+					t.Fatalf("%v: WriteSamples = %d, %v, want %d, nil", dt, n, err, end-i)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if dt.IsBinary() && buf.Len() != len(values)*dt.ByteLen() {
+				t.Fatalf("%v: wrote %d bytes, want %d", dt, buf.Len(), len(values)*dt.ByteLen())
+			}
+
+			r := NewReader(&buf, dt)
+			got, err := readAllSamples(r, size)
+			if err != io.EOF {
+				t.Fatalf("%v by %d: reading ended with %v, want io.EOF", dt, size, err)
+			}
+			assertSameFloat64s(t, got, values)
+			if n, err := r.ReadSamples(make([]float64, 1)); n != 0 || err != io.EOF {
+				t.Errorf("%v by %d: ReadSamples after the end = %d, %v, want 0, io.EOF", dt, size, n, err)
+			}
+		}
+	}
+}
+
+func TestStreamEmpty(t *testing.T) {
+	for _, dt := range dataTypes {
+		r := NewReader(strings.NewReader(""), dt)
+		if n, err := r.ReadSamples(make([]float64, 4)); n != 0 || err != io.EOF {
+			t.Errorf("%v: ReadSamples of empty data = %d, %v, want 0, io.EOF", dt, n, err)
+		}
+	}
+}
+
+func TestStreamPartialLastSample(t *testing.T) {
+	values := streamValues(10)
+	for _, dt := range []DataType{DSB, DFB, DDB} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, dt)
+		if _, err := w.WriteSamples(values); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		full := buf.Bytes()
+		for extra := 1; extra < dt.ByteLen(); extra++ {
+			data := append(append([]byte(nil), full...), full[:extra]...)
+			r := NewReader(bytes.NewReader(data), dt)
+			got, err := readAllSamples(r, 4)
+			if !errors.Is(err, ErrTruncated) {
+				t.Errorf("%v with %d extra bytes: reading ended with %v, want ErrTruncated", dt, extra, err)
+			}
+			assertSameFloat64s(t, got, values)
+			if _, err := r.ReadSamples(make([]float64, 1)); !errors.Is(err, ErrTruncated) {
+				t.Errorf("%v with %d extra bytes: ReadSamples after ErrTruncated returned %v", dt, extra, err)
+			}
+		}
+	}
+
+	// the last line of ASCII data needs no line ending.
+	for _, dt := range []DataType{DSA, DFA, DDA} {
+		r := NewReader(strings.NewReader("1\n-2\r\n3"), dt)
+		got, err := readAllSamples(r, 2)
+		if err != io.EOF {
+			t.Errorf("%v: reading ended with %v, want io.EOF", dt, err)
+		}
+		assertSameFloat64s(t, got, []float64{1, -2, 3})
+	}
+}
+
+func TestStreamUnknownDataType(t *testing.T) {
+	if _, err := NewReader(strings.NewReader("1\n"), DataType(-1)).ReadSamples(make([]float64, 1)); !errors.Is(err, ErrUnknownDataType) {
+		t.Errorf("ReadSamples: got %v, want ErrUnknownDataType", err)
+	}
+	w := NewWriter(io.Discard, DataType(-1))
+	if _, err := w.WriteSamples([]float64{1}); !errors.Is(err, ErrUnknownDataType) {
+		t.Errorf("WriteSamples: got %v, want ErrUnknownDataType", err)
+	}
+	if err := w.Flush(); !errors.Is(err, ErrUnknownDataType) {
+		t.Errorf("Flush: got %v, want ErrUnknownDataType", err)
+	}
+}
@@ -0,0 +1,443 @@
+package dxx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+
+	// maxRIFFSize is the largest size which fits in the 32-bit size fields of RIFF.
+	// Larger files are written as RF64.
+	maxRIFFSize = math.MaxUint32
+
+	// maxWAVHeaderChunkSize is the largest fmt and ds64 chunk accepted by ReadWAV.
+	// fmt is at most 18 bytes and an extension of up to 65535 bytes. ds64 is 28 bytes and a table which is empty in practice.
+	maxWAVHeaderChunkSize = 18 + math.MaxUint16
+)
+
+var (
+	ErrInvalidWAV     = errors.New("invalid WAV file")
+	ErrUnsupportedWAV = errors.New("unsupported WAV format")
+)
+
+// WAVFormat is the sample format of WAV data.
+type WAVFormat struct {
+	SampleRate int
+	Channels   int
+	// BitsPerSample is 16, 24 or 32 for PCM and 32 or 64 for IEEE float.
+	BitsPerSample int
+	// Float is true for IEEE float samples.
+	Float bool
+}
+
+// DefaultWAVFormat is the format used by WriteToFile for .wav files.
+var DefaultWAVFormat = WAVFormat{
+	SampleRate:    48000,
+	Channels:      1,
+	BitsPerSample: 32,
+	Float:         true,
+}
+
+func (f WAVFormat) validate() error {
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return ErrUnsupportedWAV
+	}
+	// the header stores the channel count and the frame length in 16 bits, and the byte rate in 32 bits.
+	if f.Channels > math.MaxUint16 {
+		return fmt.Errorf("%w: %d channels", ErrUnsupportedWAV, f.Channels)
+	}
+	if f.blockAlign() > math.MaxUint16 {
+		return fmt.Errorf("%w: frames of %d bytes", ErrUnsupportedWAV, f.blockAlign())
+	}
+	if int64(f.SampleRate)*int64(f.blockAlign()) > math.MaxUint32 {
+		return fmt.Errorf("%w: %d bytes per second", ErrUnsupportedWAV, int64(f.SampleRate)*int64(f.blockAlign()))
+	}
+	switch {
+	case !f.Float && (f.BitsPerSample == 16 || f.BitsPerSample == 24 || f.BitsPerSample == 32):
+		return nil
+	case f.Float && (f.BitsPerSample == 32 || f.BitsPerSample == 64):
+		return nil
+	default:
+		return ErrUnsupportedWAV
+	}
+}
+
+// blockAlign returns the byte length of a frame.
+func (f WAVFormat) blockAlign() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+// extensible reports whether the format must be written as WAVE_FORMAT_EXTENSIBLE.
+func (f WAVFormat) extensible() bool {
+	return f.Channels > 2 || (!f.Float && f.BitsPerSample > 16)
+}
+
+// ReadWAV reads RIFF WAV or RF64 data from reader.
+// The samples of all channels are returned interleaved.
+// PCM samples are scaled to [-1, 1). IEEE float samples are returned as stored.
+// A data chunk which is shorter than its size or ends in the middle of a frame is reported as ErrTruncated.
+func ReadWAV(r io.Reader) ([]float64, WAVFormat, error) {
+	var f WAVFormat
+	br := bufio.NewReader(r)
+
+	var hdr [12]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, f, ErrInvalidWAV
+	}
+	rf64 := string(hdr[0:4]) == "RF64"
+	if (string(hdr[0:4]) != "RIFF" && !rf64) || string(hdr[8:12]) != "WAVE" {
+		return nil, f, ErrInvalidWAV
+	}
+
+	var (
+		dataSize64 uint64
+		haveFmt    bool
+	)
+	for {
+		var ch [8]byte
+		if _, err := io.ReadFull(br, ch[:]); err != nil {
+			return nil, f, ErrInvalidWAV
+		}
+		id := string(ch[0:4])
+		size := uint64(binary.LittleEndian.Uint32(ch[4:8]))
+		switch id {
+		case "ds64":
+			b, err := readWAVHeaderChunk(br, id, size, 24)
+			if err != nil {
+				return nil, f, err
+			}
+			dataSize64 = binary.LittleEndian.Uint64(b[8:16])
+		case "fmt ":
+			b, err := readWAVHeaderChunk(br, id, size, 16)
+			if err != nil {
+				return nil, f, err
+			}
+			if f, err = parseWAVFmt(b); err != nil {
+				return nil, f, err
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return nil, f, ErrInvalidWAV
+			}
+			if rf64 && size == maxRIFFSize {
+				size = dataSize64
+			}
+			data, err := readWAVData(br, f, size, !rf64 && size == maxRIFFSize)
+			return data, f, err
+		default:
+			if _, err := io.CopyN(ioutil.Discard, br, int64(size)); err != nil {
+				return nil, f, ErrInvalidWAV
+			}
+		}
+		// chunks are aligned to 2 bytes.
+		if size%2 == 1 {
+			if _, err := br.Discard(1); err != nil {
+				return nil, f, ErrInvalidWAV
+			}
+		}
+	}
+}
+
+// readWAVHeaderChunk reads the content of the header chunk id of size bytes, which must be at least minSize bytes.
+// The size comes from the file, so chunks larger than maxWAVHeaderChunkSize are rejected
+// instead of allocating whatever a corrupt file asks for.
+func readWAVHeaderChunk(r io.Reader, id string, size uint64, minSize int) ([]byte, error) {
+	if size < uint64(minSize) || size > maxWAVHeaderChunkSize {
+		return nil, fmt.Errorf("%w: %q chunk of %d bytes", ErrInvalidWAV, id, size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrInvalidWAV
+	}
+	return b, nil
+}
+
+func parseWAVFmt(b []byte) (WAVFormat, error) {
+	tag := binary.LittleEndian.Uint16(b[0:2])
+	f := WAVFormat{
+		Channels:      int(binary.LittleEndian.Uint16(b[2:4])),
+		SampleRate:    int(binary.LittleEndian.Uint32(b[4:8])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(b[14:16])),
+	}
+	if tag == wavFormatExtensible {
+		if len(b) < 40 {
+			return f, ErrInvalidWAV
+		}
+		// the first 2 bytes of the sub format GUID are the format tag.
+		tag = binary.LittleEndian.Uint16(b[24:26])
+	}
+	switch tag {
+	case wavFormatPCM:
+	case wavFormatIEEEFloat:
+		f.Float = true
+	default:
+		return f, ErrUnsupportedWAV
+	}
+	return f, f.validate()
+}
+
+// readWAVData reads the samples of the data chunk of size bytes.
+// If the data ends before size bytes or in the middle of a frame, readWAVData returns ErrTruncated.
+// unknownSize is set for a RIFF data size of 0xFFFFFFFF, which recorders streaming a file of unknown length write:
+// the data is then read to the end.
+func readWAVData(r io.Reader, f WAVFormat, size uint64, unknownSize bool) ([]float64, error) {
+	bl := f.BitsPerSample / 8
+	buf := make([]byte, bl*f.Channels*1024)
+	var (
+		data []float64
+		read uint64
+	)
+	if !unknownSize {
+		r = io.LimitReader(r, int64(size))
+	}
+	for {
+		n, err := io.ReadFull(r, buf)
+		read += uint64(n)
+		if n%f.blockAlign() != 0 {
+			return nil, fmt.Errorf("%w: the last frame of the WAV data has %d of %d bytes", ErrTruncated, n%f.blockAlign(), f.blockAlign())
+		}
+		for i := 0; i < n; i += bl {
+			data = append(data, decodeWAVSample(buf[i:i+bl], f))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if !unknownSize && read < size {
+				return nil, fmt.Errorf("%w: the WAV data chunk has %d of %d bytes", ErrTruncated, read, size)
+			}
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func decodeWAVSample(b []byte, f WAVFormat) float64 {
+	switch {
+	case f.Float && f.BitsPerSample == 64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case f.Float:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case f.BitsPerSample == 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case f.BitsPerSample == 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// WriteWAV writes interleaved samples to writer as WAV data of the specified format.
+// PCM samples are clipped to [-1, 1] before they are scaled to integers.
+// Data larger than 4 GiB is written as RF64.
+// IEEE float data has the cbSize field and the fact chunk which the formats other than PCM require.
+func WriteWAV(w io.Writer, f WAVFormat, data []float64) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	if len(data)%f.Channels != 0 {
+		return ErrChannelLength
+	}
+	bw := bufio.NewWriter(w)
+
+	fmtSize := uint64(16)
+	switch {
+	case f.extensible():
+		fmtSize = 40
+	case f.Float:
+		// formats other than PCM have cbSize.
+		fmtSize = 18
+	}
+	// formats other than PCM have a fact chunk of the number of frames.
+	factSize := uint64(0)
+	if f.Float {
+		factSize = 8 + 4
+	}
+	frames := uint64(len(data) / f.Channels)
+	dataSize := uint64(len(data)) * uint64(f.BitsPerSample/8)
+	riffSize := 4 + 8 + fmtSize + factSize + 8 + dataSize + dataSize%2
+	rf64 := riffSize > maxRIFFSize
+	if rf64 {
+		riffSize += 8 + 28
+	}
+
+	var b []byte
+	if rf64 {
+		b = append(b, "RF64"...)
+		b = appendUint32(b, maxRIFFSize)
+		b = append(b, "WAVE"...)
+		b = append(b, "ds64"...)
+		b = appendUint32(b, 28)
+		b = appendUint64(b, riffSize)
+		b = appendUint64(b, dataSize)
+		b = appendUint64(b, frames)
+		b = appendUint32(b, 0)
+	} else {
+		b = append(b, "RIFF"...)
+		b = appendUint32(b, uint32(riffSize))
+		b = append(b, "WAVE"...)
+	}
+
+	tag := uint16(wavFormatPCM)
+	if f.Float {
+		tag = wavFormatIEEEFloat
+	}
+	b = append(b, "fmt "...)
+	b = appendUint32(b, uint32(fmtSize))
+	if f.extensible() {
+		b = appendUint16(b, wavFormatExtensible)
+	} else {
+		b = appendUint16(b, tag)
+	}
+	b = appendUint16(b, uint16(f.Channels))
+	b = appendUint32(b, uint32(f.SampleRate))
+	b = appendUint32(b, uint32(f.SampleRate*f.blockAlign()))
+	b = appendUint16(b, uint16(f.blockAlign()))
+	b = appendUint16(b, uint16(f.BitsPerSample))
+	if f.extensible() {
+		var mask uint32
+		if f.Channels <= 18 {
+			// the first channels of the standard speaker layout.
+			mask = 1<<uint(f.Channels) - 1
+		}
+		b = appendUint16(b, 22)
+		b = appendUint16(b, uint16(f.BitsPerSample))
+		b = appendUint32(b, mask)
+		// KSDATAFORMAT_SUBTYPE_PCM or KSDATAFORMAT_SUBTYPE_IEEE_FLOAT
+		b = appendUint16(b, tag)
+		b = append(b, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71)
+	} else if f.Float {
+		b = appendUint16(b, 0)
+	}
+	if f.Float {
+		b = append(b, "fact"...)
+		b = appendUint32(b, 4)
+		if rf64 {
+			// the number of frames is in ds64.
+			b = appendUint32(b, maxRIFFSize)
+		} else {
+			b = appendUint32(b, uint32(frames))
+		}
+	}
+
+	b = append(b, "data"...)
+	if rf64 {
+		b = appendUint32(b, maxRIFFSize)
+	} else {
+		b = appendUint32(b, uint32(dataSize))
+	}
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+
+	s := make([]byte, f.BitsPerSample/8)
+	for _, v := range data {
+		encodeWAVSample(s, v, f)
+		if _, err := bw.Write(s); err != nil {
+			return err
+		}
+	}
+	if dataSize%2 == 1 {
+		if err := bw.WriteByte(0); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func encodeWAVSample(b []byte, v float64, f WAVFormat) {
+	switch {
+	case f.Float && f.BitsPerSample == 64:
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	case f.Float:
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+	case f.BitsPerSample == 16:
+		binary.LittleEndian.PutUint16(b, uint16(int16(quantizePCM(v, 16))))
+	case f.BitsPerSample == 24:
+		u := uint32(int32(quantizePCM(v, 24)))
+		b[0], b[1], b[2] = byte(u), byte(u>>8), byte(u>>16)
+	default:
+		binary.LittleEndian.PutUint32(b, uint32(int32(quantizePCM(v, 32))))
+	}
+}
+
+// quantizePCM clips v to [-1, 1] and scales it to a signed integer of the specified bit length.
+func quantizePCM(v float64, bits uint) int64 {
+	full := float64(int64(1) << (bits - 1))
+	v = math.Round(v * full)
+	if math.IsNaN(v) {
+		return 0
+	}
+	if v > full-1 {
+		return int64(full - 1)
+	}
+	if v < -full {
+		return int64(-full)
+	}
+	return int64(v)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
+
+// isWAV reports whether the filename has .wav extension.
+func isWAV(filename string) bool {
+	return strings.EqualFold(ext(filename), "wav")
+}
+
+// readWAVFile reads .wav file and returns the interleaved samples and the format.
+func readWAVFile(filename string) ([]float64, WAVFormat, error) {
+	return readWAVFileFS(osFS{}, filename)
+}
+
+func readWAVFileFS(fsys fs.FS, filename string) ([]float64, WAVFormat, error) {
+	f, err := openData(fsys, filename)
+	if err != nil {
+		return nil, WAVFormat{}, err
+	}
+	defer f.Close()
+	return ReadWAV(f)
+}
+
+// writeWAVFile writes interleaved samples to .wav file atomically.
+func writeWAVFile(filename string, format WAVFormat, data []float64, opts *Options) error {
+	return writeFileAtomic(filename, opts, NoPredictor, func(w io.Writer) error {
+		return WriteWAV(w, format, data)
+	})
+}
+
+// wavFormatFromMeta returns DefaultWAVFormat with the sampling rate and the channel count of the metadata.
+func wavFormatFromMeta(m *Meta) WAVFormat {
+	f := DefaultWAVFormat
+	if m == nil {
+		return f
+	}
+	if m.SamplingRate != 0 {
+		f.SampleRate = m.SamplingRate
+	}
+	if m.Channels != 0 {
+		f.Channels = m.Channels
+	}
+	return f
+}
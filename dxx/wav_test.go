@@ -0,0 +1,180 @@
+package dxx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadWAVOversizedChunk(t *testing.T) {
+	var good bytes.Buffer
+	if err := WriteWAV(&good, WAVFormat{Channels: 1, SampleRate: 48000, BitsPerSample: 16}, []float64{0, 0.5, -0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadWAV(bytes.NewReader(good.Bytes())); err != nil {
+		t.Fatalf("ReadWAV of a valid file: %v", err)
+	}
+
+	for _, c := range []struct {
+		riff, id string
+	}{
+		{"RIFF", "fmt "},
+		{"RF64", "ds64"},
+	} {
+		// a header whose chunk claims nearly 4 GiB
+		b := append([]byte(c.riff), 0, 0, 0, 0)
+		b = append(b, "WAVE"...)
+		b = append(b, c.id...)
+		b = appendUint32(b, 0xFFFFFFF0)
+		b = append(b, make([]byte, 64)...)
+		if _, _, err := ReadWAV(bytes.NewReader(b)); !errors.Is(err, ErrInvalidWAV) {
+			t.Errorf("%q chunk of 0xFFFFFFF0 bytes: got %v, want ErrInvalidWAV", c.id, err)
+		}
+	}
+}
+
+// wavValues returns the interleaved samples of frames frames of the channels, which every format stores exactly.
+func wavValues(channels, frames int) []float64 {
+	data := make([]float64, channels*frames)
+	for i := range data {
+		data[i] = float64(i%257-128) / 128 * 0.75
+	}
+	data[0], data[1] = -1, 0.5
+	return data
+}
+
+// toRF64 rewrites the RIFF WAV data b, whose fmt chunk starts at byte 12, as RF64 with a ds64 chunk.
+func toRF64(t *testing.T, b []byte, dataSize, frames uint64) []byte {
+	t.Helper()
+	i := bytes.Index(b, []byte("data"))
+	if i < 0 {
+		t.Fatal("no data chunk")
+	}
+	out := append([]byte("RF64"), 0xFF, 0xFF, 0xFF, 0xFF)
+	out = append(out, "WAVE"...)
+	out = append(out, "ds64"...)
+	out = appendUint32(out, 28)
+	out = appendUint64(out, uint64(len(b))+36-8)
+	out = appendUint64(out, dataSize)
+	out = appendUint64(out, frames)
+	out = appendUint32(out, 0)
+	out = append(out, b[12:i]...)
+	out = append(out, "data"...)
+	out = appendUint32(out, 0xFFFFFFFF)
+	return append(out, b[i+8:]...)
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	for _, f := range []WAVFormat{
+		{SampleRate: 48000, Channels: 1, BitsPerSample: 16},
+		{SampleRate: 44100, Channels: 2, BitsPerSample: 16},
+		{SampleRate: 48000, Channels: 1, BitsPerSample: 24},
+		{SampleRate: 96000, Channels: 2, BitsPerSample: 32},
+		{SampleRate: 48000, Channels: 1, BitsPerSample: 32, Float: true},
+		{SampleRate: 48000, Channels: 2, BitsPerSample: 64, Float: true},
+		// WAVE_FORMAT_EXTENSIBLE
+		{SampleRate: 48000, Channels: 6, BitsPerSample: 16},
+		{SampleRate: 48000, Channels: 4, BitsPerSample: 24},
+		{SampleRate: 48000, Channels: 3, BitsPerSample: 32, Float: true},
+	} {
+		data := wavValues(f.Channels, 1001)
+		var buf bytes.Buffer
+		if err := WriteWAV(&buf, f, data); err != nil {
+			t.Fatalf("%+v: %v", f, err)
+		}
+		tag := binary.LittleEndian.Uint16(buf.Bytes()[20:22])
+		if want := f.Channels > 2 || (!f.Float && f.BitsPerSample > 16); want != (tag == wavFormatExtensible) {
+			t.Errorf("%+v: format tag %#x", f, tag)
+		}
+		frames := uint64(len(data) / f.Channels)
+		dataSize := uint64(len(data) * f.BitsPerSample / 8)
+		for _, c := range []struct {
+			name string
+			b    []byte
+		}{
+			{"RIFF", buf.Bytes()},
+			{"RF64", toRF64(t, buf.Bytes(), dataSize, frames)},
+		} {
+			got, gotFormat, err := ReadWAV(bytes.NewReader(c.b))
+			if err != nil {
+				t.Fatalf("%+v %s: %v", f, c.name, err)
+			}
+			if gotFormat != f {
+				t.Errorf("%+v %s: read the format %+v", f, c.name, gotFormat)
+			}
+			assertSameFloat64s(t, got, data)
+		}
+	}
+}
+
+// TestWriteWAVHeaderLimits checks that formats whose header fields overflow are rejected.
+func TestWriteWAVHeaderLimits(t *testing.T) {
+	for _, c := range []struct {
+		f  WAVFormat
+		ok bool
+	}{
+		// frames of 65528 bytes
+		{WAVFormat{SampleRate: 8000, Channels: 8191, BitsPerSample: 64, Float: true}, true},
+		// frames of 65536 bytes
+		{WAVFormat{SampleRate: 8000, Channels: 8192, BitsPerSample: 64, Float: true}, false},
+		{WAVFormat{SampleRate: 8000, Channels: 65536, BitsPerSample: 16}, false},
+		// 4294967296 bytes per second
+		{WAVFormat{SampleRate: 1 << 30, Channels: 1, BitsPerSample: 32}, false},
+	} {
+		err := WriteWAV(io.Discard, c.f, make([]float64, c.f.Channels))
+		if c.ok && err != nil {
+			t.Errorf("%d channels of %d bits at %d Hz: %v", c.f.Channels, c.f.BitsPerSample, c.f.SampleRate, err)
+		}
+		if !c.ok && !errors.Is(err, ErrUnsupportedWAV) {
+			t.Errorf("%d channels of %d bits at %d Hz: got %v, want ErrUnsupportedWAV", c.f.Channels, c.f.BitsPerSample, c.f.SampleRate, err)
+		}
+	}
+}
+
+func TestReadWAVTruncated(t *testing.T) {
+	for _, f := range []WAVFormat{
+		{SampleRate: 48000, Channels: 1, BitsPerSample: 16},
+		{SampleRate: 48000, Channels: 2, BitsPerSample: 24},
+		{SampleRate: 48000, Channels: 4, BitsPerSample: 32, Float: true},
+	} {
+		data := wavValues(f.Channels, 100)
+		var buf bytes.Buffer
+		if err := WriteWAV(&buf, f, data); err != nil {
+			t.Fatal(err)
+		}
+		b := buf.Bytes()
+		// a partial frame, a missing frame, and the data cut in the middle of a sample
+		for _, cut := range []int{1, f.blockAlign(), f.blockAlign() + 1} {
+			if _, _, err := ReadWAV(bytes.NewReader(b[:len(b)-cut])); !errors.Is(err, ErrTruncated) {
+				t.Errorf("%+v without the last %d bytes: got %v, want ErrTruncated", f, cut, err)
+			}
+		}
+		rf64 := toRF64(t, b, uint64(len(data)*f.BitsPerSample/8), 100)
+		if _, _, err := ReadWAV(bytes.NewReader(rf64[:len(rf64)-f.blockAlign()])); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%+v RF64 without the last frame: got %v, want ErrTruncated", f, err)
+		}
+	}
+}
+
+// TestReadWAVUnknownSize checks that a RIFF data size of 0xFFFFFFFF is read to the end of the data.
+func TestReadWAVUnknownSize(t *testing.T) {
+	f := WAVFormat{SampleRate: 48000, Channels: 2, BitsPerSample: 16}
+	data := wavValues(2, 100)
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, f, data); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	i := bytes.Index(b, []byte("data"))
+	binary.LittleEndian.PutUint32(b[i+4:], 0xFFFFFFFF)
+	got, _, err := ReadWAV(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameFloat64s(t, got, data)
+	if _, _, err := ReadWAV(bytes.NewReader(b[:len(b)-1])); !errors.Is(err, ErrTruncated) {
+		t.Errorf("a partial frame: got %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,119 @@
+package spatial
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// checkpointInterval is the least interval between the checkpoints of a rendering.
+// It is a variable for the tests.
+var checkpointInterval = time.Second
+
+// The files of an interrupted rendering of the output name, e.g. out.part.DDB and out.DDB.ckpt for out.DDB.
+// The part file holds the samples written so far, and the checkpoint file records how to continue it.
+func partName(name string) string {
+	e := filepath.Ext(name)
+	return strings.TrimSuffix(name, e) + ".part" + e
+}
+
+func checkpointName(name string) string { return name + ".ckpt" }
+
+// checkpoint is the state of a rendering after its first Next segments.
+type checkpoint struct {
+	// Params identifies the rendering. A checkpoint of other parameters is not resumed.
+	Params map[string]string `json:"params"`
+	// DataType is the data type of the output.
+	DataType string `json:"data_type"`
+	// Next is the index of the first segment which is not rendered yet.
+	Next int `json:"next"`
+	// Written is the number of samples in the part file.
+	Written int `json:"written"`
+	// Skip is the number of leading samples still to be dropped.
+	Skip int `json:"skip"`
+	// Off and Pending are the samples rendered but not written yet, as little-endian float64s.
+	Off     int    `json:"off"`
+	Pending []byte `json:"pending"`
+}
+
+// loadCheckpoint reads the checkpoint of the output name.
+// It returns nil if there is no checkpoint for the parameters.
+func loadCheckpoint(name string, dt dxx.DataType, params map[string]string) (*checkpoint, error) {
+	b, err := os.ReadFile(checkpointName(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		// a damaged checkpoint only means starting over.
+		return nil, nil
+	}
+	if c.DataType != dt.String() || !reflect.DeepEqual(c.Params, params) || len(c.Pending)%8 != 0 {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// save writes the checkpoint atomically.
+func (c *checkpoint) save(name string) error {
+	f, err := dxx.CreateAtomic(checkpointName(name), nil)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if err := json.NewEncoder(f).Encode(c); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// pending returns the samples rendered but not written yet.
+func (c *checkpoint) pending() []float64 {
+	buf := make([]float64, len(c.Pending)/8)
+	conv.DecodeFloat64s(buf, c.Pending, binary.LittleEndian)
+	return buf
+}
+
+// setPending records the samples rendered but not written yet.
+func (c *checkpoint) setPending(buf []float64) {
+	c.Pending = make([]byte, len(buf)*8)
+	conv.EncodeFloat64s(c.Pending, buf, binary.LittleEndian)
+}
+
+// removeCheckpoint removes the files of an interrupted rendering of the output name.
+func removeCheckpoint(name string) error {
+	for _, n := range []string{partName(name), checkpointName(name)} {
+		if err := os.Remove(n); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderedLen returns the number of samples of the output name if it has been rendered by the creator with the params,
+// as recorded in its metadata sidecar, which is written after the output is complete.
+// An output which cannot be read is rendered again.
+func renderedLen(name, creator string, params map[string]string) (int, bool) {
+	meta, err := dxx.ReadMeta(name)
+	if err != nil || meta == nil || meta.Creator != creator || !reflect.DeepEqual(meta.Params, params) {
+		return 0, false
+	}
+	f, err := dxx.Open(name)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	return f.Len(), true
+}
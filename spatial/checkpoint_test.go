@@ -0,0 +1,293 @@
+package spatial
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// discardStderr discards the reports of the renderers until the end of the test.
+func discardStderr(tb testing.TB) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = devNull
+	tb.Cleanup(func() {
+		os.Stderr = stderr
+		devNull.Close()
+	})
+}
+
+var errInterrupted = errors.New("interrupted")
+
+// interruptedSet fails with errInterrupted after left SLTFs are got.
+type interruptedSet struct {
+	SLTFSet
+	left int
+}
+
+// interruptAfter returns the set of c which interrupts the rendering of c after n SLTFs are got for the segments,
+// in addition to those got by Validate.
+func interruptAfter(t *testing.T, c RenderConfig, n int) *interruptedSet {
+	t.Helper()
+	set := newCountingSet(c.SLTFs)
+	c.SLTFs = set
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range set.gets {
+		n += k
+	}
+	return &interruptedSet{SLTFSet: set.SLTFSet, left: n}
+}
+
+func (s *interruptedSet) Get(angle Angle, ear Ear) ([]float64, error) {
+	if s.left == 0 {
+		return nil, errInterrupted
+	}
+	s.left--
+	return s.SLTFSet.Get(angle, ear)
+}
+
+// testRendering returns a configuration of a short rendering of a noise at 8000 Hz with random SLTFs of 64 samples.
+func testRendering(t *testing.T, method Method) RenderConfig {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(1))
+	sound := filepath.Join(dir, "sound.DDB")
+	if err := dxx.WriteFileWithMeta(sound, randomSignal(rng, 20000), &dxx.Meta{SamplingRate: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	sltfs := NewSLTFMem()
+	for angle := Angle(0); angle < 3600; angle++ {
+		for _, ear := range Ears {
+			sltfs.Add(angle, ear, randomSignal(rng, 64))
+		}
+	}
+	return RenderConfig{
+		Method:       method,
+		SLTFs:        sltfs,
+		Sound:        sound,
+		MoveWidth:    40,
+		MoveVelocity: 40,
+		EndAngle:     3590,
+		SamplingRate: 8000,
+		OutDir:       filepath.Join(dir, "out"),
+	}
+}
+
+func TestResumeRendering(t *testing.T) {
+	discardStderr(t)
+	defer func(d time.Duration) { checkpointInterval = d }(checkpointInterval)
+	// a checkpoint at every segment
+	checkpointInterval = 0
+
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		t.Run(string(method), func(t *testing.T) {
+			c := testRendering(t, method)
+			want, err := Render(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// interrupted after every n SLTFs, and resumed until it completes
+			for _, n := range []int{3, 17, 50} {
+				ci := c
+				ci.OutDir = filepath.Join(t.TempDir(), "out")
+				ci.Resume = true
+				var (
+					got         RenderResult
+					err         error
+					interrupted int
+				)
+				for {
+					run := ci
+					run.SLTFs = interruptAfter(t, ci, n)
+					got, err = Render(run)
+					if !errors.Is(err, errInterrupted) {
+						break
+					}
+					interrupted++
+					// samples written after the last checkpoint are discarded.
+					parts, err := filepath.Glob(filepath.Join(ci.OutDir, "*.part.*"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, part := range parts {
+						f, err := os.OpenFile(part, os.O_WRONLY|os.O_APPEND, 0)
+						if err != nil {
+							t.Fatal(err)
+						}
+						f.Write(make([]byte, 100))
+						f.Close()
+					}
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if interrupted == 0 {
+					t.Fatalf("interrupted after every %d SLTFs: never interrupted", n)
+				}
+				if len(got.Files) != len(want.Files) {
+					t.Fatalf("interrupted after every %d SLTFs: %d files, want %d", n, len(got.Files), len(want.Files))
+				}
+				for i, name := range want.Files {
+					if got.Lengths[i] != want.Lengths[i] {
+						t.Errorf("interrupted after every %d SLTFs: %s has %d samples, want %d", n, filepath.Base(name), got.Lengths[i], want.Lengths[i])
+					}
+					if !sameContent(t, got.Files[i], name) {
+						t.Errorf("interrupted after every %d SLTFs: %s differs from the uninterrupted rendering", n, filepath.Base(name))
+					}
+				}
+				if parts := partFiles(t, ci.OutDir); len(parts) != 0 {
+					t.Errorf("interrupted after every %d SLTFs: %v are left", n, parts)
+				}
+			}
+		})
+	}
+}
+
+// partFiles returns the part files and the checkpoints in dir.
+func partFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	parts, err := filepath.Glob(filepath.Join(dir, "*.part.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ckpts, err := filepath.Glob(filepath.Join(dir, "*.ckpt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(parts, ckpts...)
+}
+
+func sameContent(t *testing.T, a, b string) bool {
+	t.Helper()
+	x, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := os.ReadFile(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Equal(x, y)
+}
+
+// totalGets returns the number of SLTFs got from the set.
+func totalGets(s *countingSet) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, k := range s.gets {
+		n += k
+	}
+	return n
+}
+
+// TestRenderAgainWithoutResume checks that the rendered files and the checkpoints are kept only with Resume.
+func TestRenderAgainWithoutResume(t *testing.T) {
+	discardStderr(t)
+	defer func(d time.Duration) { checkpointInterval = d }(checkpointInterval)
+	checkpointInterval = 0
+
+	c := testRendering(t, MethodOverlapAdd)
+	result, err := Render(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := os.ReadFile(result.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a stale rendering of the same parameters, e.g. by an older renderer
+	stale := make([]byte, len(rendered))
+	if err := os.WriteFile(result.Files[0], stale, 0644); err != nil {
+		t.Fatal(err)
+	}
+	resumed := c
+	resumed.Resume = true
+	if _, err := Render(resumed); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(result.Files[0]); !bytes.Equal(b, stale) {
+		t.Errorf("Render with Resume rendered %s again", filepath.Base(result.Files[0]))
+	}
+	if _, err := Render(c); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(result.Files[0]); !bytes.Equal(b, rendered) {
+		t.Errorf("Render without Resume kept the stale %s", filepath.Base(result.Files[0]))
+	}
+
+	// an interrupted rendering starts over without Resume
+	full := newCountingSet(c.SLTFs)
+	whole := c
+	whole.SLTFs, whole.OutDir = full, filepath.Join(t.TempDir(), "out")
+	if _, err := Render(whole); err != nil {
+		t.Fatal(err)
+	}
+	c.OutDir = filepath.Join(t.TempDir(), "out")
+	interrupted := c
+	interrupted.SLTFs = interruptAfter(t, c, 100)
+	if _, err := Render(interrupted); !errors.Is(err, errInterrupted) {
+		t.Fatalf("interrupted Render: got %v", err)
+	}
+	again := newCountingSet(c.SLTFs)
+	c.SLTFs = again
+	if _, err := Render(c); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := totalGets(again), totalGets(full); got != want {
+		t.Errorf("Render without Resume after an interruption got %d SLTFs, want %d of a whole rendering", got, want)
+	}
+	if parts := partFiles(t, c.OutDir); len(parts) != 0 {
+		t.Errorf("%v are left", parts)
+	}
+}
+
+// TestResumeASCIIOutput checks that ASCII outputs, which cannot be cut at a sample, are rendered again from the start.
+func TestResumeASCIIOutput(t *testing.T) {
+	discardStderr(t)
+	defer func(d time.Duration) { checkpointInterval = d }(checkpointInterval)
+	checkpointInterval = 0
+
+	naming, err := ParseOutputNaming(`w{{.MoveWidth}}_v{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDA`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		c := testRendering(t, method)
+		c.Naming, c.Resume = naming, true
+		want, err := Render(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.OutDir = filepath.Join(t.TempDir(), "out")
+		interrupted := c
+		interrupted.SLTFs = interruptAfter(t, c, 10)
+		if _, err := Render(interrupted); !errors.Is(err, errInterrupted) {
+			t.Fatalf("%s: interrupted Render: got %v", method, err)
+		}
+		if parts := partFiles(t, c.OutDir); len(parts) != 0 {
+			t.Errorf("%s: %v are left by the interrupted rendering of ASCII outputs", method, parts)
+		}
+		got, err := Render(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, name := range want.Files {
+			if !sameContent(t, got.Files[i], name) {
+				t.Errorf("%s: %s differs from the uninterrupted rendering", method, filepath.Base(name))
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package render implements the commands which render a moving sound, fadein-fadeout and overlap-add.
+package render
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/tetsuzawa/go-soundlib/spatial"
+)
+
+var (
+	cacheSize     = flag.Int("cache", 1024, "number of SLTFs kept in memory")
+	preload       = flag.Bool("preload", false, "read all the SLTFs of the subject into memory before rendering")
+	sltfName      = flag.String("sltf-name", spatial.DefaultSLTFNaming, "text/template of the SLTF file names in the subject directory, with .Angle [0.1 deg] and .Ear")
+	samplingRate  = flag.Int("fs", spatial.DefaultSamplingRate, "sampling rate of the sound, the SLTFs and the output [Hz]")
+	preserveSound = flag.Bool("preserve-sound", false, "keep the stored values of a DSX or DFX sound instead of normalising its peak to 10000")
+	resume        = flag.Bool("resume", false, "keep the files already rendered with the same arguments and continue an interrupted rendering")
+	outName       = flag.String("out-name", spatial.DefaultOutputNaming, "text/template of the output file names in outdir, with .MoveWidth, .MoveVelocity, .EndAngle, .Direction and .Ear")
+)
+
+// Main runs the command which renders by the method, named after it.
+func Main(method spatial.Method) {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		log.Printf("Usage of %s:\n", os.Args[0])
+		log.Printf("%s subject sound_file(.DXX) move_width move_velocity end_angle outdir\n", method)
+		log.Printf("with -resume, the files already rendered with the same arguments are kept,\n")
+		log.Printf("and an interrupted rendering continues from its checkpoint. Otherwise every file is rendered again.\n")
+		flag.PrintDefaults()
+	}
+	if err := run(method); err != nil {
+		log.Println(err)
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+func run(method spatial.Method) error {
+	flag.Parse()
+	if flag.NArg() != 6 {
+		return errors.New("invalid arguments")
+	}
+	args := flag.Args()
+	subject := args[0]
+	soundName := args[1]
+	moveWidth, err := strconv.Atoi(args[2])
+	if err != nil {
+		return err
+	}
+	moveVelocity, err := strconv.Atoi(args[3])
+	if err != nil {
+		return err
+	}
+	endAngle, err := strconv.Atoi(args[4])
+	if err != nil {
+		return err
+	}
+	outDir := args[5]
+
+	c, err := newConfig(method, subject)
+	if err != nil {
+		return err
+	}
+	c.Sound = soundName
+	c.MoveWidth = moveWidth
+	c.MoveVelocity = moveVelocity
+	c.EndAngle = endAngle
+	c.OutDir = outDir
+	// Renderは描画の前に、読み込んだSLTFで設定を検証する
+	result, err := spatial.Render(c)
+	if err != nil {
+		return err
+	}
+	log.Printf("movement: %d samples = %v at %d Hz (requested %v)\n", result.MoveSamples, result.MoveDuration(), result.SamplingRate, c.MoveDuration())
+	return nil
+}
+
+// newConfig returns the configuration of the rendering by the method with the SLTFs of the subject, as specified by the flags.
+func newConfig(method spatial.Method, subject string) (spatial.RenderConfig, error) {
+	naming, err := spatial.ParseOutputNaming(*outName)
+	if err != nil {
+		return spatial.RenderConfig{}, err
+	}
+	dir, err := openSLTFDir(subject)
+	if err != nil {
+		return spatial.RenderConfig{}, err
+	}
+	c := spatial.RenderConfig{
+		Method:        method,
+		PreserveSound: *preserveSound,
+		SamplingRate:  *samplingRate,
+		Naming:        naming,
+		Resume:        *resume,
+	}
+	if *preload {
+		if c.SLTFs, err = spatial.Preload(dir); err != nil {
+			return spatial.RenderConfig{}, err
+		}
+	} else {
+		c.SLTFs = spatial.NewSLTFCache(dir, *cacheSize)
+	}
+	return c, nil
+}
+
+// openSLTFDir opens the SLTFs of the subject directory named as specified by the flags.
+func openSLTFDir(subject string) (*spatial.SLTFDir, error) {
+	naming, err := spatial.ParseSLTFNaming(*sltfName)
+	if err != nil {
+		return nil, err
+	}
+	return spatial.OpenSLTFDir(subject, naming, *samplingRate)
+}
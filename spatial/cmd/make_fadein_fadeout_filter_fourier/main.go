@@ -3,14 +3,18 @@ package main
 import (
 	"errors"
 	"flag"
+	"github.com/tetsuzawa/go-soundlib/conv"
 	"github.com/tetsuzawa/go-soundlib/dxx"
 	"github.com/tetsuzawa/go-soundlib/spatial"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 )
 
+var fullScale = flag.Bool("full-scale", false, "store the filters in [0, 1] at the full scale of the type (Q15 for .DSX, as is for .DFX and .DDX) instead of normalising .DSX to 32767 and .DFX to 10000")
+
 func init() {
 	log.SetFlags(0)
 	flag.Usage = func() {
@@ -40,11 +44,35 @@ func run() error {
 	fadeinFiltName := args[1]
 	fadeoutFiltName := args[2]
 	fadeinFilt, fadeoutFilt := spatial.GenerateFadeinFadeoutFilt(samples)
-	if err := dxx.WriteToFile(fadeinFiltName, fadeinFilt); err != nil {
-		return err
+	meta := &dxx.Meta{
+		Channels:  1,
+		Creator:   "make_fadein_fadeout_filter_fourier",
+		CreatedAt: time.Now(),
+		Params:    map[string]string{"samples": args[0]},
 	}
-	if err := dxx.WriteToFile(fadeoutFiltName, fadeoutFilt); err != nil {
-		return err
+	// the filters range over [0, 1]. By default they are stored at the levels of dxx.WriteToFile.
+	var opts *dxx.Options
+	if *fullScale {
+		meta.Params["full_scale"] = "true"
+		opts = &dxx.Options{Scaler: &conv.Scaler{Mode: conv.FullScale}}
+	}
+	for _, f := range []struct {
+		name string
+		data []float64
+	}{
+		{fadeinFiltName, fadeinFilt},
+		{fadeoutFiltName, fadeoutFilt},
+	} {
+		if err := dxx.WriteToFileWithOptions(f.name, f.data, opts); err != nil {
+			return err
+		}
+		if opts != nil && opts.Scaler.Clipped > 0 {
+			log.Printf("warning: %s: %d samples clipped to the full scale\n", f.name, opts.Scaler.Clipped)
+			opts.Scaler.Clipped = 0
+		}
+		if err := dxx.WriteMeta(f.name, meta); err != nil {
+			return err
+		}
 	}
 	return nil
 }
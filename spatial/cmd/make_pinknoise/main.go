@@ -7,11 +7,18 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
+	"github.com/tetsuzawa/go-soundlib/conv"
 	"github.com/tetsuzawa/go-soundlib/dxx"
 	"github.com/tetsuzawa/go-soundlib/spatial"
 )
 
+var (
+	samplingRate = flag.Int("fs", spatial.DefaultSamplingRate, "sampling rate [Hz]")
+	fullScale    = flag.Bool("full-scale", false, "store the noise in [-1, 1] at the full scale of the type (Q15 for .DSX, as is for .DFX and .DDX) instead of normalising .DSX to 32767 and .DFX to 10000")
+)
+
 func init() {
 	log.SetFlags(0)
 	flag.Usage = func() {
@@ -40,7 +47,28 @@ func run() error {
 	}
 	outPath := args[1]
 
-	const fs = 48000
-	pinkNoise := spatial.PinkNoise(samples, fs)
-	return dxx.WriteToFile(outPath, pinkNoise)
+	if *samplingRate <= 0 {
+		return errors.New("sampling rate must be positive")
+	}
+	pinkNoise := spatial.PinkNoise(samples, *samplingRate)
+	meta := &dxx.Meta{
+		SamplingRate: *samplingRate,
+		Channels:     1,
+		Creator:      "make_pinknoise",
+		CreatedAt:    time.Now(),
+		Params:       map[string]string{"samples": args[0], "fs": strconv.Itoa(*samplingRate)},
+	}
+	// the noise is normalised to [-1, 1]. By default it is stored at the levels of dxx.WriteToFile.
+	var opts *dxx.Options
+	if *fullScale {
+		meta.Params["full_scale"] = "true"
+		opts = &dxx.Options{Scaler: &conv.Scaler{Mode: conv.FullScale}}
+	}
+	if err := dxx.WriteToFileWithOptions(outPath, pinkNoise, opts); err != nil {
+		return err
+	}
+	if opts != nil && opts.Scaler.Clipped > 0 {
+		log.Printf("warning: %s: %d samples clipped to the full scale\n", outPath, opts.Scaler.Clipped)
+	}
+	return dxx.WriteMeta(outPath, meta)
 }
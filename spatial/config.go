@@ -0,0 +1,255 @@
+package spatial
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+var (
+	ErrUnknownMethod       = errors.New("unknown rendering method")
+	ErrNoSLTFs             = errors.New("no SLTF set")
+	ErrInvalidWidth        = errors.New("move width must be positive")
+	ErrInvalidVelocity     = errors.New("invalid move velocity")
+	ErrInvalidAngle        = errors.New("end angle must be in [0, 3600)")
+	ErrInvalidSamplingRate = errors.New("sampling rate must be positive")
+	ErrEmptySLTF           = errors.New("empty SLTF")
+)
+
+// DefaultSamplingRate is the sampling rate [Hz] of a RenderConfig without one.
+const DefaultSamplingRate = 48000
+
+// ErrSoundTooShort is the error of a sound which is shorter than the rendering needs.
+type ErrSoundTooShort struct {
+	// Need and Have are the numbers of samples needed and in the sound.
+	Need, Have int
+}
+
+func (e ErrSoundTooShort) Error() string {
+	return fmt.Sprintf("sound too short: need %d samples, have %d", e.Need, e.Have)
+}
+
+// Method is the method of rendering a moving sound.
+type Method string
+
+const (
+	// MethodFadeinFadeout crossfades the sound convolved with the SLTF of each angle. See FadeinFadeout.
+	MethodFadeinFadeout Method = "fadein-fadeout"
+	// MethodOverlapAdd overlap-adds the segments of the sound convolved with the SLTF of each angle. See OverlapAdd.
+	MethodOverlapAdd Method = "overlap-add"
+)
+
+// RenderConfig is the configuration of the rendering of a moving sound.
+type RenderConfig struct {
+	// Method is the method of rendering. It is set by FadeinFadeout and OverlapAdd.
+	Method Method
+	// SLTFs are the SLTFs of the subject.
+	// Use OpenSLTFDir for a subject directory, wrapped in NewSLTFCache or Preload to read each SLTF only once.
+	SLTFs SLTFSet
+	// Sound is the name of the .DXX file of the sound source, which may be compressed. WAV sounds are not supported.
+	// A DSX or DFX sound is normalised by its peak absolute value to an amplitude of 10000, as dxx.ReadFromFile reads it,
+	// unless PreserveSound is set. A DDX sound is kept.
+	Sound string
+	// PreserveSound keeps the stored values of a DSX or DFX sound, as the SLTFs are kept,
+	// so that the level of the rendered files follows the level of the sound.
+	PreserveSound bool
+	// MoveWidth is the width of the movement [0.1 deg].
+	MoveWidth int
+	// MoveVelocity is the velocity of the movement [0.1 deg/sec].
+	MoveVelocity int
+	// EndAngle is the angle at which the movement ends [0.1 deg].
+	EndAngle int
+	// SamplingRate is the sampling rate of the sound, the SLTFs and the rendered files [Hz].
+	// The default is DefaultSamplingRate.
+	SamplingRate int
+	// OutDir is the directory of the rendered files.
+	OutDir string
+	// Naming names the rendered files in OutDir. The default is DefaultOutputNaming.
+	Naming *OutputNaming
+	// Resume continues an earlier rendering of the same configuration instead of rendering every file again:
+	// the rendered files whose metadata records the same parameters are kept,
+	// and the binary files whose rendering was interrupted are continued from their last checkpoint.
+	// The files are kept even if the renderer has changed since, so it is off by default.
+	Resume bool
+}
+
+func (c *RenderConfig) samplingRate() int {
+	if c.SamplingRate == 0 {
+		return DefaultSamplingRate
+	}
+	return c.SamplingRate
+}
+
+// Validate checks the configuration before rendering.
+// It gets every SLTF the rendering uses, failing if any of them is missing or empty,
+// computes the number of samples the rendering reads from the sound with them,
+// and returns ErrSoundTooShort if the sound has fewer.
+// It returns ErrDuplicateName if the naming gives a file in OutDir which was rendered with other parameters.
+func (c *RenderConfig) Validate() error {
+	if c.Method != MethodFadeinFadeout && c.Method != MethodOverlapAdd {
+		return fmt.Errorf("%w: %q", ErrUnknownMethod, c.Method)
+	}
+	if c.SLTFs == nil {
+		return ErrNoSLTFs
+	}
+	if c.MoveWidth <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidWidth, c.MoveWidth)
+	}
+	if c.MoveVelocity <= 0 {
+		return fmt.Errorf("%w: %d: must be positive", ErrInvalidVelocity, c.MoveVelocity)
+	}
+	if c.SamplingRate < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidSamplingRate, c.SamplingRate)
+	}
+	if c.EndAngle < 0 || c.EndAngle >= 3600 {
+		return fmt.Errorf("%w: %d", ErrInvalidAngle, c.EndAngle)
+	}
+	if n := c.dwellingLen(); n < 1 {
+		return fmt.Errorf("%w: %d: too fast for a width of %d, the sound stays at each angle for %d samples", ErrInvalidVelocity, c.MoveVelocity, c.MoveWidth, n)
+	}
+	if _, err := outputPaths(c.Naming, c.OutDir, OutputNameData{MoveWidth: c.MoveWidth, MoveVelocity: c.MoveVelocity, EndAngle: c.EndAngle}); err != nil {
+		return err
+	}
+
+	// only the length of the sound is needed, which does not depend on its scaling.
+	// It is opened before the SLTFs are read, so that a sound which cannot be opened fails at once.
+	sound, err := dxx.Open(c.Sound)
+	if err != nil {
+		return err
+	}
+	have := sound.Len()
+	if err := sound.Close(); err != nil {
+		return err
+	}
+	need, err := c.soundLen()
+	if err != nil {
+		return err
+	}
+	soundMeta, err := dxx.ReadMeta(c.Sound)
+	if err != nil {
+		return err
+	}
+	if err := checkSamplingRate(c.Sound, soundMeta, c.samplingRate()); err != nil {
+		return err
+	}
+	if have < need {
+		return fmt.Errorf("%s: %w", c.Sound, ErrSoundTooShort{Need: need, Have: have})
+	}
+	return nil
+}
+
+// soundLen returns the number of samples the rendering reads from the sound.
+// It gets every SLTF the rendering uses, and fails if any of them is missing or empty.
+func (c *RenderConfig) soundLen() (int, error) {
+	lens := make(map[sltfKey]int)
+	sltfLen := func(angle Angle, ear Ear) (int, error) {
+		k := sltfKey{angle.Norm(), ear}
+		if n, ok := lens[k]; ok {
+			return n, nil
+		}
+		sltf, err := c.SLTFs.Get(angle, ear)
+		if err != nil {
+			return 0, err
+		}
+		if len(sltf) == 0 {
+			return 0, fmt.Errorf("%w: angle %d ear %s", ErrEmptySLTF, k.angle, ear)
+		}
+		lens[k] = len(sltf)
+		return len(sltf), nil
+	}
+
+	if c.Method == MethodFadeinFadeout {
+		// the segments read further into the sound with longer SLTFs
+		t := newFadeinFadeoutTiming(c.MoveWidth, c.MoveVelocity, c.samplingRate())
+		need := 0
+		for _, direction := range Directions {
+			for _, ear := range Ears {
+				for i := 0; i < t.segments(); i++ {
+					n, err := sltfLen(Angle(c.EndAngle+t.angle(i, direction)), ear)
+					if err != nil {
+						return 0, err
+					}
+					if end := t.segmentEnd(i, n); end > need {
+						need = end
+					}
+				}
+			}
+		}
+		return need, nil
+	}
+
+	t := newOverlapAddTiming(c.MoveWidth, c.MoveVelocity, c.samplingRate())
+	// OverlapAdd extends the output by the SLTF of angle 0 and the left ear.
+	if _, err := sltfLen(0, Left); err != nil {
+		return 0, err
+	}
+	for _, direction := range Directions {
+		for _, ear := range Ears {
+			for i := 0; i < t.moveWidth; i++ {
+				if _, err := sltfLen(Angle(c.EndAngle+t.angle(i, direction)), ear); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	return t.soundLen(), nil
+}
+
+// dwellingLen returns the number of samples the sound stays at each angle, which must be positive.
+// The crossfade of FadeinFadeout is a 64th of it and may be empty.
+func (c *RenderConfig) dwellingLen() int {
+	if c.Method == MethodFadeinFadeout {
+		return newFadeinFadeoutTiming(c.MoveWidth, c.MoveVelocity, c.samplingRate()).dwelling
+	}
+	return newOverlapAddTiming(c.MoveWidth, c.MoveVelocity, c.samplingRate()).perDeg
+}
+
+// Render renders the moving sound by c.Method.
+func Render(c RenderConfig) (RenderResult, error) {
+	switch c.Method {
+	case MethodFadeinFadeout:
+		return FadeinFadeout(c)
+	case MethodOverlapAdd:
+		return OverlapAdd(c)
+	default:
+		return RenderResult{}, fmt.Errorf("%w: %q", ErrUnknownMethod, c.Method)
+	}
+}
+
+// RenderResult reports what a rendering produced.
+type RenderResult struct {
+	// Files are the names of the rendered files, and Lengths are their numbers of samples.
+	Files   []string
+	Lengths []int
+	// SamplingRate is the sampling rate of the rendered files [Hz].
+	SamplingRate int
+	// MoveSamples is the number of samples over which the sound moves in the rendered files.
+	// It differs from the requested movement, since the sound stays at each angle for a whole number of samples,
+	// and FadeinFadeout renders about twice the requested movement. See FadeinFadeout.
+	// The files are longer than the movement by the SLTF or the first dwelling, and those of OverlapAdd
+	// by the samples of the requested movement left after the whole segments, which are silent.
+	MoveSamples int
+}
+
+// MoveDuration returns the duration of the movement actually produced.
+func (r RenderResult) MoveDuration() time.Duration {
+	return samplesToDuration(r.MoveSamples, r.SamplingRate)
+}
+
+// MoveDuration returns the requested duration of the movement, MoveWidth / MoveVelocity.
+func (c *RenderConfig) MoveDuration() time.Duration {
+	if c.MoveVelocity <= 0 {
+		return 0
+	}
+	return time.Duration(int64(c.MoveWidth) * int64(time.Second) / int64(c.MoveVelocity))
+}
+
+// samplesToDuration returns the duration of n samples at the sampling rate [Hz], rounded down to a nanosecond.
+func samplesToDuration(n, samplingRate int) time.Duration {
+	if samplingRate <= 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(time.Second) / int64(samplingRate))
+}
@@ -0,0 +1,148 @@
+package spatial
+
+import (
+	"errors"
+	"io/fs"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// withSLTF returns a copy of the SLTFs of c in which the SLTF of the angle and the ear is data, or missing if data is nil.
+func withSLTF(c RenderConfig, angle Angle, ear Ear, data []float64) SLTFSet {
+	m := NewSLTFMem()
+	for _, a := range c.SLTFs.Angles() {
+		for _, e := range Ears {
+			if a == angle.Norm() && e == ear {
+				continue
+			}
+			if sltf, err := c.SLTFs.Get(a, e); err == nil {
+				m.Add(a, e, sltf)
+			}
+		}
+	}
+	if data != nil {
+		m.Add(angle, ear, data)
+	}
+	return m
+}
+
+func TestValidateSLTFs(t *testing.T) {
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		t.Run(string(method), func(t *testing.T) {
+			c := testRendering(t, method)
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			// the SLTFs of every angle of the movement and every ear are used, including [3551, 3600) and [0, 29]
+			for _, s := range []struct {
+				angle Angle
+				ear   Ear
+			}{{3590, Left}, {3560, Right}, {3551, Left}, {20, Right}, {29, Left}} {
+				c := c
+				c.SLTFs = withSLTF(c, s.angle, s.ear, nil)
+				if err := c.Validate(); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Validate without the SLTF of angle %d ear %s: got %v, want fs.ErrNotExist", s.angle, s.ear, err)
+				}
+				c.SLTFs = withSLTF(c, s.angle, s.ear, []float64{})
+				if err := c.Validate(); !errors.Is(err, ErrEmptySLTF) {
+					t.Errorf("Validate with the empty SLTF of angle %d ear %s: got %v, want ErrEmptySLTF", s.angle, s.ear, err)
+				}
+			}
+			// the SLTFs out of the movement are not used, except the one of angle 0 and the left ear by OverlapAdd
+			c.SLTFs = withSLTF(c, 2000, Left, nil)
+			if err := c.Validate(); err != nil {
+				t.Errorf("Validate without an unused SLTF: %v", err)
+			}
+			c.EndAngle = 1000
+			c.SLTFs = withSLTF(c, 0, Left, nil)
+			if err := c.Validate(); method == MethodOverlapAdd && !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Validate without the SLTF of angle 0: got %v, want fs.ErrNotExist", err)
+			} else if method == MethodFadeinFadeout && err != nil {
+				t.Errorf("Validate without the SLTF of angle 0: %v", err)
+			}
+		})
+	}
+}
+
+// TestValidateSoundLen checks that FadeinFadeout needs the sound for the longest SLTF at the segment it is used.
+func TestValidateSoundLen(t *testing.T) {
+	c := testRendering(t, MethodFadeinFadeout)
+	// 98 samples at each of the 81 angles: the last of the segments, at 4 deg, reads (96 + 1)*80 + 96*2 + 64*3 + 1 samples
+	const need = (96+1)*80 + 96*2 + 64*3 + 1
+	sound := func(n int) {
+		t.Helper()
+		rng := rand.New(rand.NewSource(1))
+		if err := dxx.WriteFileWithMeta(c.Sound, randomSignal(rng, n), &dxx.Meta{SamplingRate: 8000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sound(need)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sound(need - 1)
+	var tooShort ErrSoundTooShort
+	if err := c.Validate(); !errors.As(err, &tooShort) || tooShort.Need != need || tooShort.Have != need-1 {
+		t.Errorf("Validate with %d samples: got %v, want the need of %d samples", need-1, err, need)
+	}
+
+	sound(need)
+	long := make([]float64, 74)
+	long[0] = 1
+	// the SLTF of the end angle is used only by the first segment
+	first := c
+	first.SLTFs = withSLTF(c, 3590, Right, long)
+	if err := first.Validate(); err != nil {
+		t.Errorf("Validate with a longer SLTF at the first segment: %v", err)
+	}
+	// the SLTF of 4 deg is used by the last segment counterclockwise
+	last := c
+	last.SLTFs = withSLTF(c, 3590-40, Right, long)
+	if err := last.Validate(); !errors.As(err, &tooShort) || tooShort.Need != need+30 {
+		t.Errorf("Validate with a longer SLTF at the last segment: got %v, want the need of %d samples", err, need+30)
+	}
+	// and the rendering reads that far
+	c.SLTFs = last.SLTFs
+	c.OutDir = filepath.Join(t.TempDir(), "out")
+	sound(need + 30)
+	discardStderr(t)
+	if _, err := Render(c); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoveDuration(t *testing.T) {
+	for _, c := range []struct {
+		moveWidth, moveVelocity int
+		want                    time.Duration
+	}{
+		{40, 40, time.Second},
+		// rounded down to a nanosecond
+		{10, 3, 3333333333 * time.Nanosecond},
+		{7, 3, 2333333333 * time.Nanosecond},
+		{10, 0, 0},
+	} {
+		rc := RenderConfig{MoveWidth: c.moveWidth, MoveVelocity: c.moveVelocity}
+		if got := rc.MoveDuration(); got != c.want {
+			t.Errorf("MoveDuration of width %d velocity %d = %v, want %v", c.moveWidth, c.moveVelocity, got, c.want)
+		}
+	}
+	for _, c := range []struct {
+		samples, samplingRate int
+		want                  time.Duration
+	}{
+		{18662, 8000, 2332750 * time.Microsecond},
+		{1, 48000, 20833 * time.Nanosecond},
+		{160000, 48000, 3333333333 * time.Nanosecond},
+		{100, 0, 0},
+	} {
+		r := RenderResult{MoveSamples: c.samples, SamplingRate: c.samplingRate}
+		if got := r.MoveDuration(); got != c.want {
+			t.Errorf("MoveDuration of %d samples at %d Hz = %v, want %v", c.samples, c.samplingRate, got, c.want)
+		}
+	}
+}
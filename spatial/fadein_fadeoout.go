@@ -4,106 +4,222 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"strconv"
 
 	"github.com/tetsuzawa/go-soundlib/dxx"
 )
 
-func FadeinFadeout(subject, soundName string, moveWidth, moveVelocity, endAngle int, outDir string) error {
-	const (
-		repeatTimes  = 1
-		samplingFreq = 48 // [kHz]
-	)
+// fadeinFadeoutCreator is the creator recorded in the metadata of the rendered files.
+const fadeinFadeoutCreator = "spatial.FadeinFadeout"
+
+// fadeinFadeoutTiming is the timing of FadeinFadeout in samples.
+type fadeinFadeoutTiming struct {
+	// moveAngle is the number of angles in each way of the movement.
+	moveAngle, moveWidth int
+	// dwelling is the nominal number of samples at each angle, which is the duration and the crossfade to the next angle.
+	dwelling, duration, overlap int
+}
+
+func newFadeinFadeoutTiming(moveWidth, moveVelocity, samplingRate int) fadeinFadeoutTiming {
+	const repeatTimes = 1
 
-	// 移動時間 [ms]
-	var moveTime float64 = float64(moveWidth) * 1000.0 / float64(moveVelocity)
 	// 移動角度
 	var moveAngle int = moveWidth*repeatTimes + 1
 
 	// 1度動くのに必要なサンプル数
-	// [ms]*[kHz] / [deg] = [sample/deg]
-	var dwellingSamples int = int(moveTime) * samplingFreq / (moveWidth*repeatTimes*2 + 1)
+	// 移動時間 [sec] = moveWidth / moveVelocity は丸めずに有理数のまま計算する
+	// [0.1deg]*[sample/sec] / [0.1deg/sec] / [deg] = [sample/deg]
+	var dwellingSamples int = moveWidth * samplingRate / (moveVelocity * (moveWidth*repeatTimes*2 + 1))
 	var durationSamples int = dwellingSamples * 63 / 64
 	var overlapSamples int = dwellingSamples * 1 / 64
 
+	return fadeinFadeoutTiming{moveAngle, moveWidth, dwellingSamples, durationSamples, overlapSamples}
+}
+
+// segments returns the number of segments, which is the number of angles of the movement there and back.
+func (t fadeinFadeoutTiming) segments() int {
+	return t.moveAngle*2 - 1
+}
+
+// angle returns the angle [0.1 deg] relative to the end angle of the segment i in the direction.
+func (t fadeinFadeoutTiming) angle(i int, direction string) int {
+	moveWidth := t.moveWidth
+	// ノコギリ波の生成
+	dataAngle := i % ((moveWidth * 2) * 2)
+	// ノコギリ波から三角波を生成
+	if dataAngle > moveWidth*2 {
+		dataAngle = (moveWidth*2)*2 - dataAngle
+	}
+	if direction == "cc" {
+		dataAngle = -dataAngle
+	}
+	dataAngle = dataAngle / 2
+	if dataAngle < 0 {
+		dataAngle += 3600
+	}
+	return dataAngle
+}
+
+// segmentEnd returns the end of the samples read from the sound for the segment i with an SLTF of sltfLen samples.
+func (t fadeinFadeoutTiming) segmentEnd(i, sltfLen int) int {
+	return (t.duration+t.overlap)*i + t.duration*2 + sltfLen*3 + 1
+}
+
+// moveSamples returns the number of samples over which the sound moves, as FadeinFadeout lays them out.
+// Each angle appends its sustain and fadeout, duration*2-overlap samples, to the end of the output,
+// while its fadein is added at the nominal position dwelling*angle. The movement is thus about twice
+// the nominal dwelling*(moveAngle*2-1) samples, which is the requested width / velocity.
+func (t fadeinFadeoutTiming) moveSamples() int {
+	return (t.duration*2 - t.overlap) * (t.moveAngle*2 - 1)
+}
+
+// FadeinFadeout renders the moving sound of c by crossfading the sound convolved with the SLTF of each angle.
+// The segment of each angle is appended to the end of the output, so the rendered movement is about twice
+// as long as c.MoveWidth / c.MoveVelocity, and the fadein of each angle is added at its nominal position
+// rather than over the fadeout of the previous angle. RenderResult.MoveSamples reports the rendered movement.
+// c.Method is ignored, and c is validated by Validate first.
+// If c.Resume is set, an interrupted rendering is continued instead of rendered again. See RenderConfig.Resume.
+func FadeinFadeout(c RenderConfig) (RenderResult, error) {
+	c.Method = MethodFadeinFadeout
+	if err := c.Validate(); err != nil {
+		return RenderResult{}, err
+	}
+	sltfs, soundName, moveWidth, moveVelocity, endAngle := c.SLTFs, c.Sound, c.MoveWidth, c.MoveVelocity, c.EndAngle
+
+	// サンプリング周波数 [sample/sec]
+	samplingRate := c.samplingRate()
+	t := newFadeinFadeoutTiming(moveWidth, moveVelocity, samplingRate)
+	dwellingSamples, durationSamples, overlapSamples := t.dwelling, t.duration, t.overlap
+
 	fadeinFilter, fadeoutFilter := GenerateFadeinFadeoutFilt(overlapSamples)
 
-	// 音データの読み込み
-	sound, err := dxx.ReadFromFile(soundName)
+	// 音データを開く（角度ごとに必要な区間だけを読み込む）
+	sound, err := openSound(&c)
 	if err != nil {
-		return err
+		return RenderResult{}, err
+	}
+	defer sound.Close()
+	soundMeta, err := dxx.ReadMeta(soundName)
+	if err != nil {
+		return RenderResult{}, err
+	}
+	if err := checkSamplingRate(soundName, soundMeta, samplingRate); err != nil {
+		return RenderResult{}, err
 	}
 
-	for _, direction := range []string{"c", "cc"} {
-		for _, LR := range []string{"L", "R"} {
-			moveOut := make([]float64, dwellingSamples, int(moveTime)*samplingFreq)
-			usedAngles := make([]int, moveAngle*2-1)
-
-			for angle := 0; angle < (moveAngle*2 - 1); angle++ {
-				// ノコギリ波の生成
-				dataAngle := angle % ((moveWidth * 2) * 2)
-				// ノコギリ波から三角波を生成
-				if dataAngle > moveWidth*2 {
-					dataAngle = (moveWidth*2)*2 - dataAngle
-				}
-				if direction == "cc" {
-					dataAngle = -dataAngle
-				}
-				dataAngle = dataAngle / 2
-				if dataAngle < 0 {
-					dataAngle += 3600
-				}
+	result := RenderResult{SamplingRate: samplingRate, MoveSamples: t.moveSamples()}
 
-				// SLTFの読み込み
-				SLTFName := fmt.Sprintf("%s/SLTF/SLTF_%d_%s.DDB", subject, (endAngle+dataAngle)%3600, LR)
-				SLTF, err := dxx.ReadFromFile(SLTFName)
-				if err != nil {
-					return err
-				}
-				usedAngles[angle] = (endAngle + dataAngle) % 3600
-
-				// Fadein-Fadeout
-				// 音データと伝達関数の畳込み
-				cutSound := sound[angle*(durationSamples+overlapSamples) : durationSamples*2+angle*(durationSamples+overlapSamples)+len(SLTF)*3+1]
-				soundSLTF := LinearConvolutionTimeDomain(cutSound, SLTF)
-				// 無音区間の切り出し
-				soundSLTF = soundSLTF[len(SLTF)*2 : len(soundSLTF)-len(SLTF)*2]
-				// 前の角度のfadeout部と現在の角度のfadein部の加算
-				fadein := make([]float64, overlapSamples)
-				for i := range fadein {
-					fadein[i] = soundSLTF[i] * fadeinFilter[i]
-					moveOut[(durationSamples+overlapSamples)*angle+i] += fadein[i]
-				}
+	// 出力ファイル名の決定
+	outNames, err := outputNames(c.Naming, c.OutDir, OutputNameData{MoveWidth: moveWidth, MoveVelocity: moveVelocity, EndAngle: endAngle})
+	if err != nil {
+		return result, err
+	}
 
-				// 持続時間
-				moveOut = append(moveOut, soundSLTF[overlapSamples:len(soundSLTF)-overlapSamples]...)
+	for _, direction := range Directions {
+		for _, LR := range []string{"L", "R"} {
+			usedAngles := make([]int, t.segments())
+
+			params := map[string]string{
+				"sound":         soundName,
+				"move_width":    strconv.Itoa(moveWidth),
+				"move_velocity": strconv.Itoa(moveVelocity),
+				"end_angle":     strconv.Itoa(endAngle),
+				"sampling_rate": strconv.Itoa(samplingRate),
+				"direction":     direction,
+				"ear":           LR,
+			}
+			if subject := subjectName(sltfs); subject != "" {
+				params["subject"] = subject
+			}
+			if c.PreserveSound {
+				params["preserve_sound"] = "true"
+			}
 
-				// fadeout
-				fadeout := make([]float64, overlapSamples)
-				for i := range fadein {
-					fadeout[i] = soundSLTF[len(soundSLTF)-overlapSamples+i] * fadeoutFilter[i]
+			outName := outNames[direction][Ear(LR)]
+			// 再開する場合、前回の実行で出力済みのファイルは描画し直さない
+			if c.Resume {
+				if n, ok := renderedLen(outName, fadeinFadeoutCreator, params); ok {
+					result.Files = append(result.Files, outName)
+					result.Lengths = append(result.Lengths, n)
+					if _, err := fmt.Fprintf(os.Stderr, "%s: length=%d (already rendered)\n", outName, n); err != nil {
+						return result, err
+					}
+					continue
 				}
-				moveOut = append(moveOut, fadeout...)
 			}
 
-			// 先頭のFadein部をカット
-			out := moveOut[overlapSamples:]
-
-			// DDBへ出力
-			outName := fmt.Sprintf("%s/move_judge_w%03d_mt%03d_%s_%d_%s.DDB", outDir, moveWidth, moveVelocity, direction, endAngle, LR)
-			if err := dxx.WriteToFile(outName, out); err != nil {
-				return err
+			// DXXへ出力
+			// 先頭のFadein部はカットする
+			outLen, err := writeStream(outName, outputDataType(outName), overlapSamples, params, c.Resume, func(moveOut *overlapAddWriter, start int) error {
+				moveOut.grow(dwellingSamples)
+
+				for angle := 0; angle < t.segments(); angle++ {
+					dataAngle := t.angle(angle, direction)
+					usedAngles[angle] = (endAngle + dataAngle) % 3600
+					// 中断前に出力済みの角度は飛ばす
+					if angle < start {
+						continue
+					}
+
+					// SLTFの読み込み
+					SLTF, err := sltfs.Get(Angle(endAngle+dataAngle), Ear(LR))
+					if err != nil {
+						return err
+					}
+
+					// Fadein-Fadeout
+					// 音データと伝達関数の畳込み
+					cutSound, err := sound.Slice(angle*(durationSamples+overlapSamples), durationSamples*2+len(SLTF)*3+1)
+					if err != nil {
+						return err
+					}
+					soundSLTF := convolve(cutSound, SLTF)
+					// 無音区間の切り出し
+					soundSLTF = soundSLTF[len(SLTF)*2 : len(soundSLTF)-len(SLTF)*2]
+					// 前の角度のfadeout部と現在の角度のfadein部の加算
+					fadein := make([]float64, overlapSamples)
+					for i := range fadein {
+						fadein[i] = soundSLTF[i] * fadeinFilter[i]
+					}
+					moveOut.add((durationSamples+overlapSamples)*angle, fadein)
+
+					// 持続時間
+					moveOut.add(moveOut.len(), soundSLTF[overlapSamples:len(soundSLTF)-overlapSamples])
+
+					// fadeout
+					fadeout := make([]float64, overlapSamples)
+					for i := range fadein {
+						fadeout[i] = soundSLTF[len(soundSLTF)-overlapSamples+i] * fadeoutFilter[i]
+					}
+					moveOut.add(moveOut.len(), fadeout)
+
+					// 以降の角度で加算されない区間を出力
+					if err := moveOut.commit(angle+1, (durationSamples+overlapSamples)*(angle+1)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return result, err
+			}
+			meta := newRenderMeta(fadeinFadeoutCreator, samplingRate, soundMeta, params)
+			if err := dxx.WriteMeta(outName, meta); err != nil {
+				return result, err
 			}
-			_, err = fmt.Fprintf(os.Stderr, "%s: length=%d\n", outName, len(out))
+			result.Files = append(result.Files, outName)
+			result.Lengths = append(result.Lengths, outLen)
+			_, err = fmt.Fprintf(os.Stderr, "%s: length=%d\n", outName, outLen)
 			if err != nil {
-				return err
+				return result, err
 			}
-			_, err := fmt.Fprintf(os.Stderr, "used angle:%v\n", usedAngles)
+			_, err = fmt.Fprintf(os.Stderr, "used angle:%v\n", usedAngles)
 			if err != nil {
-				return err
+				return result, err
 			}
 		}
 	}
-	return nil
+	return result, nil
 }
 
 func GenerateFadeinFadeoutFilt(length int) (fadeinFilt, fadeoutFilt []float64) {
@@ -0,0 +1,139 @@
+package spatial
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+func TestFadeinFadeoutTiming(t *testing.T) {
+	for _, c := range []struct {
+		moveWidth, moveVelocity, samplingRate int
+		// dwelling is moveWidth*samplingRate / (moveVelocity*(moveWidth*2+1)) rounded down
+		dwelling, duration, overlap int
+		moveSamples                 int
+	}{
+		// 98.77 samples per angle
+		{40, 40, 8000, 98, 96, 1, 15471},
+		// 592.59
+		{40, 40, 48000, 592, 582, 9, 93555},
+		// 596.69 in a movement of 2.25 s
+		{90, 40, 48000, 596, 586, 9, 210503},
+		// 1244.44, where width / velocity = 2.333... s is not a whole number of milliseconds
+		{7, 3, 8000, 1244, 1224, 19, 36435},
+		// exactly 640, split into 630 and 10 without rounding
+		{10, 1, 1344, 640, 630, 10, 26250},
+	} {
+		tm := newFadeinFadeoutTiming(c.moveWidth, c.moveVelocity, c.samplingRate)
+		if tm.dwelling != c.dwelling || tm.duration != c.duration || tm.overlap != c.overlap {
+			t.Errorf("width %d velocity %d at %d Hz: dwelling, duration, overlap = %d, %d, %d, want %d, %d, %d",
+				c.moveWidth, c.moveVelocity, c.samplingRate, tm.dwelling, tm.duration, tm.overlap, c.dwelling, c.duration, c.overlap)
+		}
+		if tm.segments() != c.moveWidth*2+1 {
+			t.Errorf("width %d: %d segments, want %d", c.moveWidth, tm.segments(), c.moveWidth*2+1)
+		}
+		if got := tm.moveSamples(); got != c.moveSamples {
+			t.Errorf("width %d velocity %d at %d Hz: moveSamples() = %d, want %d", c.moveWidth, c.moveVelocity, c.samplingRate, got, c.moveSamples)
+		}
+	}
+}
+
+func TestFadeinFadeoutSegments(t *testing.T) {
+	tm := newFadeinFadeoutTiming(7, 3, 8000)
+	// each segment starts duration+overlap = 1243 samples after the previous one,
+	// and reads duration*2 samples and 3 SLTFs and 1 sample beyond its start.
+	for _, c := range []struct{ i, sltfLen, want int }{
+		{0, 64, 2448 + 192 + 1},
+		{1, 64, 1243 + 2448 + 192 + 1},
+		{14, 64, 1243*14 + 2448 + 192 + 1},
+		{14, 1, 1243*14 + 2448 + 3 + 1},
+	} {
+		if got := tm.segmentEnd(c.i, c.sltfLen); got != c.want {
+			t.Errorf("segmentEnd(%d, %d) = %d, want %d", c.i, c.sltfLen, got, c.want)
+		}
+	}
+
+	// the angle moves 0.1 deg every 2 segments, there and back
+	tm = newFadeinFadeoutTiming(2, 1, 48000)
+	for _, c := range []struct {
+		direction string
+		want      []int
+	}{
+		{"c", []int{0, 0, 1, 1, 2}},
+		{"cc", []int{0, 0, 3599, 3599, 3598}},
+	} {
+		for i, want := range c.want {
+			if got := tm.angle(i, c.direction); got != want {
+				t.Errorf("angle(%d, %s) = %d, want %d", i, c.direction, got, want)
+			}
+		}
+	}
+}
+
+// TestFadeinFadeoutLength checks the reported movement and the lengths of the files when the samples per angle are not whole.
+func TestFadeinFadeoutLength(t *testing.T) {
+	discardStderr(t)
+	c := testRendering(t, MethodFadeinFadeout)
+	c.MoveWidth, c.MoveVelocity = 7, 3
+	// segmentEnd of the last segment is 20043 samples
+	if err := dxx.WriteFileWithMeta(c.Sound, randomSignal(rand.New(rand.NewSource(3)), 20043), &dxx.Meta{SamplingRate: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Render(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MoveSamples != 36435 {
+		t.Errorf("MoveSamples = %d, want 36435", result.MoveSamples)
+	}
+	// the first dwelling, less the fadein cut at the start
+	for i, n := range result.Lengths {
+		if want := 36435 + 1244 - 19; n != want {
+			t.Errorf("%s: %d samples, want %d", result.Files[i], n, want)
+		}
+	}
+
+	// one sample less is too short
+	if err := dxx.WriteFileWithMeta(c.Sound, randomSignal(rand.New(rand.NewSource(3)), 20042), &dxx.Meta{SamplingRate: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	var tooShort ErrSoundTooShort
+	if err := c.Validate(); !errors.As(err, &tooShort) || tooShort.Need != 20043 {
+		t.Errorf("Validate of a sound of 20042 samples: got %v, want ErrSoundTooShort of 20043 samples", err)
+	}
+}
+
+// TestFadeinFadeoutShortDwelling checks that a dwelling of fewer than 64 samples is rendered with an empty crossfade,
+// and that only a dwelling of no samples is rejected.
+func TestFadeinFadeoutShortDwelling(t *testing.T) {
+	discardStderr(t)
+	if tm := newFadeinFadeoutTiming(10, 400, 48000); tm.dwelling != 57 || tm.overlap != 0 {
+		t.Errorf("width 10 velocity 400 at 48000 Hz: dwelling %d, overlap %d, want 57, 0", tm.dwelling, tm.overlap)
+	}
+
+	c := testRendering(t, MethodFadeinFadeout)
+	// 63.49 samples per angle at 8000 Hz
+	c.MoveWidth, c.MoveVelocity = 10, 60
+	result, err := Render(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MoveSamples != 62*2*21 {
+		t.Errorf("MoveSamples = %d, want %d", result.MoveSamples, 62*2*21)
+	}
+	for i, n := range result.Lengths {
+		if want := 62*2*21 + 63; n != want {
+			t.Errorf("%s: %d samples, want %d", result.Files[i], n, want)
+		}
+	}
+
+	// 0.38 samples per angle
+	c.MoveVelocity = 10000
+	err = c.Validate()
+	if !errors.Is(err, ErrInvalidVelocity) || !strings.Contains(err.Error(), "stays at each angle for 0 samples") {
+		t.Errorf("Validate of a dwelling of no samples: got %v, want ErrInvalidVelocity", err)
+	}
+}
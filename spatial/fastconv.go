@@ -0,0 +1,235 @@
+package spatial
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// Convolve returns the linear convolution of x and y. len: len(x) + len(y) - 1
+// It computes the convolution directly, by a single FFT or by overlap-save,
+// whichever is estimated to be the fastest for the lengths.
+func Convolve(x, y []float64) []float64 {
+	if len(x) < len(y) {
+		// y is the shorter, e.g. the SLTF
+		x, y = y, x
+	}
+	if len(y) == 0 {
+		return make([]float64, max0(len(x)-1))
+	}
+	method, size := selectConvolution(len(x), len(y))
+	switch method {
+	case convolutionFFT:
+		return FFTConvolve(x, y)
+	case convolutionOverlapSave:
+		return OverlapSaveConvolve(x, y, size)
+	default:
+		return LinearConvolutionTimeDomain(x, y)
+	}
+}
+
+// convolve convolves the segments of the renderers. The benchmarks replace it to compare the methods.
+var convolve = Convolve
+
+// FFTConvolve returns the linear convolution of x and y by a single FFT. len: len(x) + len(y) - 1
+// x and y are transformed together as the real and the imaginary parts of one complex signal,
+// so that it takes one forward and one inverse transform.
+func FFTConvolve(x, y []float64) []float64 {
+	if len(x) == 0 || len(y) == 0 {
+		return make([]float64, max0(len(x)+len(y)-1))
+	}
+	convLen := len(x) + len(y) - 1
+	p := planFFT(nextPow2(convLen))
+	z := make([]complex128, p.n)
+	for i, v := range x {
+		z[i] = complex(v, 0)
+	}
+	for i, v := range y {
+		z[i] += complex(0, v)
+	}
+	p.transform(z, false)
+	// as x and y are real, X[k] = (Z[k] + conj(Z[-k])) / 2 and Y[k] = (Z[k] - conj(Z[-k])) / 2i,
+	// so X[k]Y[k] = (Z[k]^2 - conj(Z[-k])^2) / 4i
+	for k := 0; k <= p.n/2; k++ {
+		j := (p.n - k) & (p.n - 1)
+		zk, zj := z[k], z[j]
+		ck, cj := complex(real(zk), -imag(zk)), complex(real(zj), -imag(zj))
+		z[k] = (zk*zk - cj*cj) * complex(0, -0.25)
+		z[j] = (zj*zj - ck*ck) * complex(0, -0.25)
+	}
+	p.transform(z, true)
+	res := make([]float64, convLen)
+	scale := 1 / float64(p.n)
+	for n := range res {
+		res[n] = real(z[n]) * scale
+	}
+	return res
+}
+
+// OverlapSaveConvolve returns the linear convolution of x and the shorter y by overlap-save. len: len(x) + len(y) - 1
+// x is convolved in blocks of fftSize, which is rounded up to a power of two of at least 2*len(y).
+// Two real blocks are transformed together as one complex block.
+func OverlapSaveConvolve(x, y []float64, fftSize int) []float64 {
+	if len(x) == 0 || len(y) == 0 {
+		return make([]float64, max0(len(x)+len(y)-1))
+	}
+	convLen := len(x) + len(y) - 1
+	if fftSize < 2*len(y) {
+		fftSize = 2 * len(y)
+	}
+	p := planFFT(nextPow2(fftSize))
+	// the first len(y)-1 samples of each block are wrapped around by the circular convolution and discarded
+	overlap := len(y) - 1
+	step := p.n - overlap
+
+	// the spectrum of y
+	spec := make([]complex128, p.n)
+	for i, v := range y {
+		spec[i] = complex(v, 0)
+	}
+	p.transform(spec, false)
+
+	res := make([]float64, convLen)
+	z := make([]complex128, p.n)
+	scale := 1 / float64(p.n)
+	// each iteration convolves the blocks which start at start and start+step as the real and the imaginary parts.
+	// a block reads x[start-overlap : start-overlap+p.n], zero outside x, and writes res[start : start+step].
+	for start := 0; start < convLen; start += step * 2 {
+		for i := range z {
+			var a, b float64
+			if n := start - overlap + i; n >= 0 && n < len(x) {
+				a = x[n]
+			}
+			if n := start + step - overlap + i; n >= 0 && n < len(x) {
+				b = x[n]
+			}
+			z[i] = complex(a, b)
+		}
+		p.transform(z, false)
+		for k := range z {
+			z[k] *= spec[k]
+		}
+		p.transform(z, true)
+		for n := 0; n < step; n++ {
+			if out := start + n; out < convLen {
+				res[out] = real(z[overlap+n]) * scale
+			}
+			if out := start + step + n; out < convLen {
+				res[out] = imag(z[overlap+n]) * scale
+			}
+		}
+	}
+	return res
+}
+
+// convolutionMethod is a method of computing a linear convolution.
+type convolutionMethod int
+
+const (
+	convolutionDirect convolutionMethod = iota
+	convolutionFFT
+	convolutionOverlapSave
+)
+
+// fftCostFactor is the cost of a complex FFT of size n, fftCostFactor * n * log2(n),
+// relative to a multiply-add of the direct convolution, measured on amd64.
+const fftCostFactor = 3
+
+// fftCost returns the estimated cost of an FFT of size n, which is a power of two.
+func fftCost(n int) float64 {
+	stages := bits.Len(uint(n)) - 1
+	if stages < 1 {
+		stages = 1
+	}
+	return fftCostFactor * float64(n) * float64(stages)
+}
+
+// selectConvolution returns the fastest estimated method of convolving a signal of n samples with one of m <= n samples,
+// and the FFT size of overlap-save.
+func selectConvolution(n, m int) (convolutionMethod, int) {
+	convLen := n + m - 1
+	method, size := convolutionDirect, 0
+	cost := float64(n) * float64(m)
+
+	fftSize := nextPow2(convLen)
+	if c := 2 * fftCost(fftSize); c < cost {
+		method, cost = convolutionFFT, c
+	}
+	// overlap-save transforms y once, and each pair of blocks forward and inverse
+	for s := nextPow2(2 * m); s < fftSize; s *= 2 {
+		step := s - m + 1
+		pairs := (convLen + 2*step - 1) / (2 * step)
+		if c := fftCost(s) * float64(1+2*pairs); c < cost {
+			method, cost, size = convolutionOverlapSave, c, s
+		}
+	}
+	return method, size
+}
+
+// fftPlan is a radix-2 FFT of a fixed size.
+type fftPlan struct {
+	n int
+	// twiddle is exp(-2πik/n) for k < n/2, and rev is the bit reversal permutation.
+	twiddle []complex128
+	rev     []int
+}
+
+// fftPlans are the plans by size shared by the convolutions.
+var fftPlans sync.Map
+
+// planFFT returns the plan of an FFT of size n, which is a power of two.
+func planFFT(n int) *fftPlan {
+	if p, ok := fftPlans.Load(n); ok {
+		return p.(*fftPlan)
+	}
+	p := &fftPlan{n: n, twiddle: make([]complex128, n/2), rev: make([]int, n)}
+	for k := range p.twiddle {
+		s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(n))
+		p.twiddle[k] = complex(c, s)
+	}
+	shift := bits.UintSize - (bits.Len(uint(n)) - 1)
+	for i := range p.rev {
+		if n > 1 {
+			p.rev[i] = int(bits.Reverse(uint(i)) >> shift)
+		}
+	}
+	v, _ := fftPlans.LoadOrStore(n, p)
+	return v.(*fftPlan)
+}
+
+// transform computes the FFT of x in place, or the inverse FFT without the 1/n scaling.
+func (p *fftPlan) transform(x []complex128, inverse bool) {
+	for i, j := range p.rev {
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= p.n; size *= 2 {
+		half, stride := size/2, p.n/size
+		for start := 0; start < p.n; start += size {
+			for k := 0; k < half; k++ {
+				w := p.twiddle[k*stride]
+				if inverse {
+					w = complex(real(w), -imag(w))
+				}
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k], x[start+k+half] = a+b, a-b
+			}
+		}
+	}
+}
+
+// nextPow2 returns the least power of two which is not less than n.
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+func max0(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,232 @@
+package spatial
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+func randomSignal(rng *rand.Rand, n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = rng.NormFloat64()
+	}
+	return x
+}
+
+// assertConvolution compares got with the direct convolution of x and y.
+// The tolerance is relative to the largest possible magnitude of a sample of the convolution.
+func assertConvolution(t *testing.T, name string, got, x, y []float64) {
+	t.Helper()
+	want := LinearConvolutionTimeDomain(x, y)
+	if len(got) != len(want) {
+		t.Fatalf("%s(%d, %d): length %d, want %d", name, len(x), len(y), len(got), len(want))
+	}
+	var ax, ay float64
+	for _, v := range x {
+		ax += math.Abs(v)
+	}
+	for _, v := range y {
+		ay += math.Abs(v)
+	}
+	tol := 1e-12 * math.Max(ax*ay, 1)
+	for i := range want {
+		if d := math.Abs(got[i] - want[i]); d > tol {
+			t.Fatalf("%s(%d, %d): sample %d is %v, want %v (error %g > %g)", name, len(x), len(y), i, got[i], want[i], d, tol)
+		}
+	}
+}
+
+// convolutionLengths are the pairs of lengths of the tests:
+// lengths of 1, uneven lengths, x shorter than y, and convolutions of a power of two and its neighbours.
+var convolutionLengths = [][2]int{
+	{1, 1}, {1, 7}, {7, 1}, {2, 2}, {3, 5}, {5, 3}, {17, 13}, {100, 33},
+	{33, 100}, {64, 512}, {512, 64}, {1000, 1},
+	// len(x) + len(y) - 1 is 2^k - 1, 2^k and 2^k + 1
+	{128, 128}, {129, 128}, {130, 128}, {512, 512}, {513, 512}, {514, 512},
+	{1535, 512}, {1536, 512}, {1537, 512}, {3584, 513}, {3585, 512},
+	{2600, 512}, {10007, 512}, {48000, 512},
+}
+
+func TestConvolve(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, l := range convolutionLengths {
+		x, y := randomSignal(rng, l[0]), randomSignal(rng, l[1])
+		assertConvolution(t, "Convolve", Convolve(x, y), x, y)
+		assertConvolution(t, "FFTConvolve", FFTConvolve(x, y), x, y)
+	}
+}
+
+func TestOverlapSaveConvolve(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, l := range convolutionLengths {
+		x, y := randomSignal(rng, l[0]), randomSignal(rng, l[1])
+		if len(x) < len(y) {
+			x, y = y, x
+		}
+		// the sizes are rounded up to a power of two of at least 2*len(y)
+		for _, size := range []int{0, 1, 2 * len(y), 3 * len(y), 4 * len(y), 1000, 4096} {
+			assertConvolution(t, fmt.Sprintf("OverlapSaveConvolve[%d]", size), OverlapSaveConvolve(x, y, size), x, y)
+		}
+	}
+}
+
+func TestConvolveEmpty(t *testing.T) {
+	x := []float64{1, 2, 3}
+	for name, f := range map[string]func(x, y []float64) []float64{
+		"Convolve":    Convolve,
+		"FFTConvolve": FFTConvolve,
+		"OverlapSaveConvolve": func(x, y []float64) []float64 {
+			return OverlapSaveConvolve(x, y, 0)
+		},
+	} {
+		if got := f(x, nil); len(got) != 2 || got[0] != 0 || got[1] != 0 {
+			t.Errorf("%s(x, nil) = %v, want [0 0]", name, got)
+		}
+		if got := f(nil, nil); len(got) != 0 {
+			t.Errorf("%s(nil, nil) = %v, want []", name, got)
+		}
+	}
+}
+
+func TestSelectConvolution(t *testing.T) {
+	for _, c := range []struct {
+		n, m int
+		want convolutionMethod
+	}{
+		{1, 1, convolutionDirect},
+		{64, 8, convolutionDirect},
+		{600, 512, convolutionFFT},
+		{48000, 512, convolutionOverlapSave},
+	} {
+		got, size := selectConvolution(c.n, c.m)
+		if got != c.want {
+			t.Errorf("selectConvolution(%d, %d) = %v, want %v", c.n, c.m, got, c.want)
+		}
+		if got == convolutionOverlapSave && (size < 2*c.m || size != nextPow2(size) || size >= nextPow2(c.n+c.m-1)) {
+			t.Errorf("selectConvolution(%d, %d): overlap-save of size %d", c.n, c.m, size)
+		}
+	}
+}
+
+// BenchmarkSweep renders a 360 deg sweep at 36 deg/sec with 512-tap SLTFs in memory,
+// with the direct convolution and with the convolution selected by Convolve.
+func BenchmarkSweep(b *testing.B) {
+	dir := b.TempDir()
+	sound := filepath.Join(dir, "sound.DDB")
+	if err := dxx.WriteToFile(sound, PinkNoise(1000000, DefaultSamplingRate)); err != nil {
+		b.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	sltfs := NewSLTFMem()
+	for angle := Angle(0); angle < 3600; angle++ {
+		for _, ear := range Ears {
+			sltfs.Add(angle, ear, randomSignal(rng, 512))
+		}
+	}
+	discardStderr(b)
+
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		for _, conv := range []struct {
+			name string
+			f    func(x, y []float64) []float64
+		}{
+			{"direct", LinearConvolutionTimeDomain},
+			{"selected", Convolve},
+		} {
+			b.Run(string(method)+"/"+conv.name, func(b *testing.B) {
+				defer func(f func(x, y []float64) []float64) { convolve = f }(convolve)
+				convolve = conv.f
+				for i := 0; i < b.N; i++ {
+					c := RenderConfig{
+						Method:       method,
+						SLTFs:        sltfs,
+						Sound:        sound,
+						MoveWidth:    3600,
+						MoveVelocity: 360,
+						OutDir:       filepath.Join(dir, fmt.Sprint(method, conv.name, i)),
+					}
+					if _, err := Render(c); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkConvolutionCrossover measures every method just below and above each point at which
+// selectConvolution changes the method for a filter of m samples, and logs the points
+// at which the selected method is not the fastest.
+// The time of each method is reported as a metric, and the benchmark itself times the selected method.
+func BenchmarkConvolutionCrossover(b *testing.B) {
+	methods := []struct {
+		method convolutionMethod
+		name   string
+	}{
+		{convolutionDirect, "direct"},
+		{convolutionFFT, "fft"},
+		{convolutionOverlapSave, "overlap-save"},
+	}
+	run := func(method convolutionMethod, x, y []float64, size int) {
+		switch method {
+		case convolutionDirect:
+			LinearConvolutionTimeDomain(x, y)
+		case convolutionFFT:
+			FFTConvolve(x, y)
+		default:
+			OverlapSaveConvolve(x, y, size)
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, m := range []int{16, 64, 512} {
+		prev, _ := selectConvolution(m, m)
+		measured := 0
+		for n := m + 1; n <= 1<<16; n++ {
+			cur, _ := selectConvolution(n, m)
+			if cur == prev {
+				continue
+			}
+			prev = cur
+			for _, n := range []int{n - 1, n} {
+				if n <= measured {
+					continue
+				}
+				measured = n
+				x, y := randomSignal(rng, n), randomSignal(rng, m)
+				selected, size := selectConvolution(n, m)
+				b.Run(fmt.Sprintf("m=%d/n=%d", m, n), func(b *testing.B) {
+					times := make([]time.Duration, len(methods))
+					fastest := 0
+					for i, mt := range methods {
+						// testing.Benchmark cannot run inside a benchmark.
+						var (
+							ops   int
+							start = time.Now()
+						)
+						for ; time.Since(start) < 20*time.Millisecond; ops++ {
+							run(mt.method, x, y, size)
+						}
+						times[i] = time.Since(start) / time.Duration(ops)
+						b.ReportMetric(float64(times[i].Nanoseconds()), mt.name+"-ns/op")
+						if times[i] < times[fastest] {
+							fastest = i
+						}
+					}
+					if times[selected] > times[fastest]*5/4 {
+						b.Logf("selected %s of %v, but %s takes %v", methods[selected].name, times[selected], methods[fastest].name, times[fastest])
+					}
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						run(selected, x, y, size)
+					}
+				})
+			}
+		}
+	}
+}
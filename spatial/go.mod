@@ -1,9 +1,9 @@
 module github.com/tetsuzawa/go-soundlib/spatial
 
-go 1.15
+go 1.18
 
 require (
 	github.com/mjibson/go-dsp v0.0.0-20180508042940-11479a337f12
-	github.com/tetsuzawa/go-soundlib/conv v0.0.0-20201112052528-458207e71658
-	github.com/tetsuzawa/go-soundlib/dxx v0.0.0-20201107045809-afaa9f209d07
+	github.com/tetsuzawa/go-soundlib/conv v0.2.0
+	github.com/tetsuzawa/go-soundlib/dxx v0.2.0
 )
@@ -0,0 +1,32 @@
+package spatial
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// checkSamplingRate returns an error if the metadata of the .DXX file declares a sampling rate other than fs.
+func checkSamplingRate(filename string, m *dxx.Meta, fs int) error {
+	if err := m.CheckSamplingRate(fs); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// newRenderMeta returns the metadata of a rendered signal.
+// The units of the source sound are propagated to the rendered signal.
+func newRenderMeta(creator string, fs int, sound *dxx.Meta, params map[string]string) *dxx.Meta {
+	m := &dxx.Meta{
+		SamplingRate: fs,
+		Channels:     1,
+		Creator:      creator,
+		CreatedAt:    time.Now(),
+		Params:       params,
+	}
+	if sound != nil {
+		m.Units = sound.Units
+	}
+	return m
+}
@@ -0,0 +1,87 @@
+package spatial
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// TestRenderMeta checks that the sampling rate and the units of the sound are passed to the sidecars of the rendered files.
+func TestRenderMeta(t *testing.T) {
+	discardStderr(t)
+	for _, c := range []struct {
+		method  Method
+		creator string
+	}{
+		{MethodFadeinFadeout, fadeinFadeoutCreator},
+		{MethodOverlapAdd, overlapAddCreator},
+	} {
+		rc := testRendering(t, c.method)
+		data, err := dxx.ReadFromFile(rc.Sound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dxx.WriteFileWithMeta(rc.Sound, data, &dxx.Meta{SamplingRate: 8000, Units: "Pa"}); err != nil {
+			t.Fatal(err)
+		}
+		result, err := Render(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.SamplingRate != 8000 {
+			t.Errorf("%s: RenderResult.SamplingRate = %d, want 8000", c.method, result.SamplingRate)
+		}
+		for _, name := range result.Files {
+			m, err := dxx.ReadMeta(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m == nil {
+				t.Fatalf("%s: no sidecar", filepath.Base(name))
+			}
+			if m.SamplingRate != 8000 || m.Channels != 1 || m.Units != "Pa" || m.Creator != c.creator || m.CreatedAt.IsZero() {
+				t.Errorf("%s: sidecar %+v", filepath.Base(name), m)
+			}
+			if m.Params["sampling_rate"] != "8000" || m.Params["sound"] != rc.Sound {
+				t.Errorf("%s: params %v", filepath.Base(name), m.Params)
+			}
+		}
+	}
+}
+
+// TestRenderSamplingRateMismatch checks that a sound declaring another sampling rate is not rendered.
+func TestRenderSamplingRateMismatch(t *testing.T) {
+	discardStderr(t)
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		for _, fs := range []int{8000, 0} {
+			c := testRendering(t, method)
+			data, err := dxx.ReadFromFile(c.Sound)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := dxx.WriteFileWithMeta(c.Sound, data, &dxx.Meta{SamplingRate: 44100}); err != nil {
+				t.Fatal(err)
+			}
+			// 0 is DefaultSamplingRate
+			c.SamplingRate = fs
+			want := fs
+			if fs == 0 {
+				want = DefaultSamplingRate
+			}
+			_, err = Render(c)
+			if !errors.Is(err, dxx.ErrSamplingRateMismatch) {
+				t.Fatalf("%s at %d Hz: got %v, want ErrSamplingRateMismatch", method, fs, err)
+			}
+			if !strings.Contains(err.Error(), c.Sound) || !strings.Contains(err.Error(), strconv.Itoa(want)+" Hz") {
+				t.Errorf("%s at %d Hz: the error %q does not tell the sound and the rate", method, fs, err)
+			}
+			if files, _ := filepath.Glob(filepath.Join(c.OutDir, "*")); len(files) != 0 {
+				t.Errorf("%s at %d Hz: %v are written", method, fs, files)
+			}
+		}
+	}
+}
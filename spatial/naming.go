@@ -0,0 +1,276 @@
+package spatial
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+var (
+	ErrDuplicateName = errors.New("template produces the same name for different files")
+)
+
+// The default naming of the SLTF files and the rendered files.
+const (
+	DefaultSLTFNaming   = `SLTF/SLTF_{{.Angle}}_{{.Ear}}.DDB`
+	DefaultOutputNaming = `move_judge_w{{printf "%03d" .MoveWidth}}_mt{{printf "%03d" .MoveVelocity}}_{{.Direction}}_{{.EndAngle}}_{{.Ear}}.DDB`
+)
+
+// SLTFNameData is the data of the template of an SLTF file name.
+type SLTFNameData struct {
+	// Angle is the angle [0.1 deg] in [0, 3600).
+	Angle Angle
+	// Ear is "L" or "R".
+	Ear Ear
+}
+
+// OutputNameData is the data of the template of a rendered file name.
+type OutputNameData struct {
+	// MoveWidth [0.1 deg], MoveVelocity [0.1 deg/sec] and EndAngle [0.1 deg] are the parameters of the rendering.
+	MoveWidth, MoveVelocity, EndAngle int
+	// Direction is "c" for clockwise or "cc" for counterclockwise.
+	Direction string
+	// Ear is "L" or "R".
+	Ear Ear
+}
+
+func (d OutputNameData) String() string {
+	return fmt.Sprintf("width %d velocity %d end angle %d direction %s ear %s", d.MoveWidth, d.MoveVelocity, d.EndAngle, d.Direction, d.Ear)
+}
+
+// Directions are the directions of the movement in the order the renderers render them.
+var Directions = []string{"c", "cc"}
+
+// SLTFNaming names the SLTF files in a subject directory by a text/template executed with SLTFNameData.
+// The names are slash-separated paths relative to the subject directory, e.g. `HRIR/az{{printf "%04d" .Angle}}_el000_{{.Ear}}.DFB`.
+// The data type of an SLTF is given by the extension of its name.
+type SLTFNaming struct {
+	t *template.Template
+}
+
+// ParseSLTFNaming parses the template of the SLTF file names.
+// It fails if the template produces the same name for different angles or ears.
+func ParseSLTFNaming(text string) (*SLTFNaming, error) {
+	t, err := template.New("sltf").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	n := &SLTFNaming{t}
+	seen := make(map[string]SLTFNameData)
+	for angle := Angle(0); angle < 3600; angle++ {
+		for _, ear := range Ears {
+			name, err := n.Name(angle, ear)
+			if err != nil {
+				return nil, err
+			}
+			if d, ok := seen[name]; ok {
+				return nil, fmt.Errorf("%w: %q for angle %d ear %s and angle %d ear %s", ErrDuplicateName, name, d.Angle, d.Ear, angle, ear)
+			}
+			seen[name] = SLTFNameData{angle, ear}
+		}
+	}
+	return n, nil
+}
+
+// defaultSLTFNaming is the naming of SLTFName.
+var defaultSLTFNaming = &SLTFNaming{template.Must(template.New("sltf").Parse(DefaultSLTFNaming))}
+
+// Name returns the name of the SLTF of the angle and the ear.
+func (n *SLTFNaming) Name(angle Angle, ear Ear) (string, error) {
+	var b strings.Builder
+	if err := n.t.Execute(&b, SLTFNameData{angle.Norm(), ear}); err != nil {
+		return "", err
+	}
+	name := b.String()
+	if !fs.ValidPath(name) || name == "." {
+		return "", fmt.Errorf("invalid SLTF file name: %q", name)
+	}
+	return name, nil
+}
+
+// OutputNaming names the rendered files by a text/template executed with OutputNameData.
+// The names are relative to the output directory, and their extension gives the data type of the output,
+// e.g. `{{.Direction}}/w{{.MoveWidth}}_{{.EndAngle}}_{{.Ear}}.DFB`.
+type OutputNaming struct {
+	t *template.Template
+}
+
+// ParseOutputNaming parses the template of the rendered file names.
+// It fails if the template produces the same name for different rendered files,
+// so that the renderings of a batch do not overwrite each other,
+// or a name whose extension is not an uncompressed DXX data type.
+// The check is only a sample: the names are compared for two values of each of MoveWidth, MoveVelocity
+// and EndAngle with every direction and ear, which finds a template ignoring any of them,
+// but not one which collapses other values, e.g. by printing only some of the digits.
+// The renderers check the names of the actual parameters against the files rendered before in the output directory.
+func ParseOutputNaming(text string) (*OutputNaming, error) {
+	t, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	n := &OutputNaming{t}
+	seen := make(map[string]OutputNameData)
+	for _, width := range []int{10, 20} {
+		for _, velocity := range []int{40, 80} {
+			for _, endAngle := range []int{0, 100} {
+				d := OutputNameData{MoveWidth: width, MoveVelocity: velocity, EndAngle: endAngle}
+				names, err := n.names(d)
+				if err != nil {
+					return nil, err
+				}
+				for _, direction := range Directions {
+					for _, ear := range Ears {
+						d.Direction, d.Ear = direction, ear
+						name := filepath.Clean(names[direction][ear])
+						if p, ok := seen[name]; ok {
+							return nil, fmt.Errorf("%w: %q for %s and %s", ErrDuplicateName, name, p, d)
+						}
+						seen[name] = d
+					}
+				}
+			}
+		}
+	}
+	return n, nil
+}
+
+// defaultOutputNaming is the naming of the rendered files if none is given.
+var defaultOutputNaming = &OutputNaming{template.Must(template.New("output").Parse(DefaultOutputNaming))}
+
+// Name returns the name of the rendered file of the data.
+func (n *OutputNaming) Name(d OutputNameData) (string, error) {
+	var b strings.Builder
+	if err := n.t.Execute(&b, d); err != nil {
+		return "", err
+	}
+	name := b.String()
+	if name == "" || filepath.IsAbs(name) {
+		return "", fmt.Errorf("invalid output file name: %q", name)
+	}
+	if c, _ := dxx.CompressionOf(name); c != dxx.NoCompression {
+		return "", fmt.Errorf("%s: rendered files cannot be compressed", name)
+	}
+	if _, err := dxx.StringToDataType(strings.TrimPrefix(filepath.Ext(name), ".")); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return name, nil
+}
+
+// names returns the names of the rendered files of every direction and ear for the parameters of d.
+// It fails if any two of them are the same.
+func (n *OutputNaming) names(d OutputNameData) (map[string]map[Ear]string, error) {
+	names := make(map[string]map[Ear]string)
+	seen := make(map[string]OutputNameData)
+	for _, direction := range Directions {
+		names[direction] = make(map[Ear]string)
+		for _, ear := range Ears {
+			d.Direction, d.Ear = direction, ear
+			name, err := n.Name(d)
+			if err != nil {
+				return nil, err
+			}
+			if p, ok := seen[filepath.Clean(name)]; ok {
+				return nil, fmt.Errorf("%w: %q for direction %s ear %s and direction %s ear %s", ErrDuplicateName, name, p.Direction, p.Ear, direction, ear)
+			}
+			seen[filepath.Clean(name)] = d
+			names[direction][ear] = name
+		}
+	}
+	return names, nil
+}
+
+// outputPaths returns the paths of the rendered files in outDir by the naming, or DefaultOutputNaming if it is nil.
+// It fails with ErrDuplicateName if a file rendered before at any of the paths, or an interrupted rendering of it,
+// has other parameters of the naming, so that a batch of renderings in outDir does not overwrite its own files.
+func outputPaths(naming *OutputNaming, outDir string, d OutputNameData) (map[string]map[Ear]string, error) {
+	if naming == nil {
+		naming = defaultOutputNaming
+	}
+	names, err := naming.names(d)
+	if err != nil {
+		return nil, err
+	}
+	for _, direction := range Directions {
+		for _, ear := range Ears {
+			path := filepath.Join(outDir, names[direction][ear])
+			names[direction][ear] = path
+			d.Direction, d.Ear = direction, ear
+			rendered, err := renderedNameData(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range rendered {
+				if p != d {
+					return nil, fmt.Errorf("%w: %q for %s and %s rendered before", ErrDuplicateName, path, d, p)
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// outputNames returns the paths of the rendered files like outputPaths, and creates their directories.
+func outputNames(naming *OutputNaming, outDir string, d OutputNameData) (map[string]map[Ear]string, error) {
+	names, err := outputPaths(naming, outDir, d)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range names {
+		for _, name := range m {
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return names, nil
+}
+
+// renderedNameData returns the naming data of the parameters recorded in the metadata of the rendered file
+// and in the checkpoint of its interrupted rendering.
+func renderedNameData(name string) ([]OutputNameData, error) {
+	var records []map[string]string
+	m, err := dxx.ReadMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil && (m.Creator == fadeinFadeoutCreator || m.Creator == overlapAddCreator) {
+		records = append(records, m.Params)
+	}
+	if b, err := os.ReadFile(checkpointName(name)); err == nil {
+		var c checkpoint
+		if json.Unmarshal(b, &c) == nil {
+			records = append(records, c.Params)
+		}
+	}
+
+	var ds []OutputNameData
+	for _, params := range records {
+		d := OutputNameData{Direction: params["direction"], Ear: Ear(params["ear"])}
+		var err error
+		if d.MoveWidth, err = strconv.Atoi(params["move_width"]); err != nil {
+			continue
+		}
+		if d.MoveVelocity, err = strconv.Atoi(params["move_velocity"]); err != nil {
+			continue
+		}
+		if d.EndAngle, err = strconv.Atoi(params["end_angle"]); err != nil {
+			continue
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// outputDataType returns the data type of the rendered file name checked by OutputNaming.Name.
+func outputDataType(name string) dxx.DataType {
+	dt, _ := dxx.StringToDataType(strings.TrimPrefix(filepath.Ext(name), "."))
+	return dt
+}
@@ -0,0 +1,204 @@
+package spatial
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+func TestParseSLTFNaming(t *testing.T) {
+	if _, err := ParseSLTFNaming(DefaultSLTFNaming); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		`SLTF_{{.Ear}}.DDB`,
+		`SLTF_{{.Angle}}.DDB`,
+		// 0.1 deg steps collapse
+		`SLTF_{{printf "%.3s" (print .Angle)}}_{{.Ear}}.DDB`,
+	} {
+		if _, err := ParseSLTFNaming(text); !errors.Is(err, ErrDuplicateName) {
+			t.Errorf("ParseSLTFNaming(%q): got %v, want ErrDuplicateName", text, err)
+		}
+	}
+	for _, text := range []string{
+		`{{.Angle`,
+		`SLTF_{{.Elevation}}_{{.Angle}}_{{.Ear}}.DDB`,
+		`../SLTF_{{.Angle}}_{{.Ear}}.DDB`,
+		`/SLTF_{{.Angle}}_{{.Ear}}.DDB`,
+		`SLTF/./{{.Angle}}_{{.Ear}}.DDB`,
+	} {
+		if _, err := ParseSLTFNaming(text); err == nil || errors.Is(err, ErrDuplicateName) {
+			t.Errorf("ParseSLTFNaming(%q): got %v, want an invalid name", text, err)
+		}
+	}
+}
+
+func TestParseOutputNaming(t *testing.T) {
+	if _, err := ParseOutputNaming(DefaultOutputNaming); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}.DDB`,
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Ear}}.DDB`,
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.Direction}}_{{.Ear}}.DDB`,
+		`{{.MoveWidth}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDB`,
+		`{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDB`,
+		// the same name after cleaning
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}/{{if eq .Ear "L"}}.{{end}}/{{.Direction}}.DDB`,
+	} {
+		if _, err := ParseOutputNaming(text); !errors.Is(err, ErrDuplicateName) {
+			t.Errorf("ParseOutputNaming(%q): got %v, want ErrDuplicateName", text, err)
+		}
+	}
+	for _, text := range []string{
+		`{{.MoveWidth`,
+		`{{.Subject}}_{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDB`,
+		`/{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDB`,
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDB.gz`,
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.wav`,
+		`{{.MoveWidth}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}`,
+	} {
+		if _, err := ParseOutputNaming(text); err == nil || errors.Is(err, ErrDuplicateName) {
+			t.Errorf("ParseOutputNaming(%q): got %v, want an invalid name", text, err)
+		}
+	}
+}
+
+// TestRenderDuplicateName renders a batch whose naming collapses widths which ParseOutputNaming does not sample.
+func TestRenderDuplicateName(t *testing.T) {
+	discardStderr(t)
+	// 10 and 20 are named 01 and 02, but 30 and 35 are both named 03.
+	naming, err := ParseOutputNaming(`w{{slice (printf "%03d" .MoveWidth) 0 2}}_{{.MoveVelocity}}_{{.EndAngle}}_{{.Direction}}_{{.Ear}}.DDB`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testRendering(t, MethodOverlapAdd)
+	c.Naming, c.MoveWidth = naming, 30
+	if _, err := Render(c); err != nil {
+		t.Fatal(err)
+	}
+	// rendering the same parameters again is not a duplicate
+	if _, err := Render(c); err != nil {
+		t.Fatal(err)
+	}
+	other := c
+	other.MoveWidth = 35
+	if err := other.Validate(); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("Validate of width 35 after width 30: got %v, want ErrDuplicateName", err)
+	}
+	if _, err := Render(other); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("Render of width 35 after width 30: got %v, want ErrDuplicateName", err)
+	}
+	// another directory is another batch
+	other.OutDir = filepath.Join(t.TempDir(), "out")
+	if _, err := Render(other); err != nil {
+		t.Error(err)
+	}
+
+	// an interrupted rendering is recorded by its checkpoint
+	defer func(d time.Duration) { checkpointInterval = d }(checkpointInterval)
+	checkpointInterval = 0
+	c.OutDir, c.Resume = filepath.Join(t.TempDir(), "out"), true
+	interrupted := c
+	interrupted.SLTFs = interruptAfter(t, c, 10)
+	if _, err := Render(interrupted); !errors.Is(err, errInterrupted) {
+		t.Fatalf("interrupted Render: got %v", err)
+	}
+	other.OutDir = c.OutDir
+	if _, err := Render(other); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("Render of width 35 after an interrupted width 30: got %v, want ErrDuplicateName", err)
+	}
+	if _, err := Render(c); err != nil {
+		t.Errorf("resuming width 30: %v", err)
+	}
+}
+
+// TestRenderDFBLayout renders from a subject directory of DFB SLTFs in a layout of its own to DFB files in another.
+func TestRenderDFBLayout(t *testing.T) {
+	discardStderr(t)
+	const sltfText = `HRIR/az{{printf "%04d" .Angle}}_el000_{{.Ear}}.DFB`
+	subject := t.TempDir()
+	files := levelSLTFs(t, sltfText, 0.02)
+	for name, f := range files {
+		filename := filepath.Join(subject, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, f.Data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sltfNaming, err := ParseSLTFNaming(sltfText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outNaming, err := ParseOutputNaming(`{{.Direction}}/w{{.MoveWidth}}_v{{.MoveVelocity}}_{{.EndAngle}}_{{.Ear}}.DFB`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := OpenSLTFDir(subject, sltfNaming, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.Len() != 7200 {
+		t.Fatalf("%d SLTFs in the subject directory, want 7200", dir.Len())
+	}
+
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		c := testRendering(t, method)
+		c.SLTFs, c.Naming, c.EndAngle = NewSLTFCache(dir, 128), outNaming, 3500
+		got, err := Render(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the same SLTFs in memory, as stored in float32
+		mem := NewSLTFMem()
+		for angle := Angle(0); angle < 3600; angle++ {
+			for _, ear := range Ears {
+				v := float64(float32(0.02 * sltfLevel(angle, ear)))
+				mem.Add(angle, ear, []float64{v, float64(float32(v / 2)), 0})
+			}
+		}
+		ref := c
+		ref.SLTFs, ref.OutDir = mem, filepath.Join(t.TempDir(), "out")
+		want, err := Render(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, name := range got.Files {
+			rel, err := filepath.Rel(c.OutDir, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := OutputNameData{MoveWidth: c.MoveWidth, MoveVelocity: c.MoveVelocity, EndAngle: c.EndAngle, Direction: Directions[i/2], Ear: Ears[i%2]}
+			if wantRel, _ := outNaming.Name(d); rel != filepath.FromSlash(wantRel) {
+				t.Errorf("%s: rendered %s, want %s", method, rel, wantRel)
+			}
+			if !sameContent(t, name, want.Files[i]) {
+				t.Errorf("%s: %s differs from the rendering with the SLTFs in memory", method, rel)
+			}
+		}
+		// the right ear is 12 dB below the left
+		for i := 0; i < len(got.Files); i += 2 {
+			l, err := dxx.ReadFromFileWithOptions(got.Files[i], &dxx.Options{Scaler: &conv.Scaler{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := dxx.ReadFromFileWithOptions(got.Files[i+1], &dxx.Options{Scaler: &conv.Scaler{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pl, pr := conv.MaxAbs(l), conv.MaxAbs(r); math.Abs(pl/pr-4) > 1e-5 {
+				t.Errorf("%s: the peaks of %s and %s are %v and %v, want a ratio of 4", method, got.Files[i], got.Files[i+1], pl, pr)
+			}
+		}
+	}
+}
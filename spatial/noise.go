@@ -24,7 +24,7 @@ func PinkNoise(samples, fs int) []float64 {
 			out[i] = rand.NormFloat64() + Sum(xs)
 		}
 	}
-	normFactor := conv.MaxFloat64s(conv.AbsFloat64s(out))
+	normFactor := conv.MaxAbs(out)
 	for i, v := range out {
 		out[i] = v / normFactor
 	}
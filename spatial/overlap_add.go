@@ -3,85 +3,182 @@ package spatial
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/tetsuzawa/go-soundlib/dxx"
 )
 
-func OverlapAdd(subject, soundName string, moveWidth, moveVelocity, endAngle int, outDir string) error {
-	// サンプリング周波数 [sample/sec]
-	const samplingFreq = 48000
-	// 移動時間 [sec]
-	var moveTime float64 = float64(moveWidth) / float64(moveVelocity)
+// overlapAddCreator is the creator recorded in the metadata of the rendered files.
+const overlapAddCreator = "spatial.OverlapAdd"
+
+// overlapAddTiming is the timing of OverlapAdd in samples.
+type overlapAddTiming struct {
+	// moveSamples is the number of samples of the movement, and perDeg is that at each angle.
+	moveSamples, perDeg int
+	moveWidth           int
+}
+
+func newOverlapAddTiming(moveWidth, moveVelocity, samplingRate int) overlapAddTiming {
 	// 移動時間 [sample]
-	var moveSamples int = int(moveTime * samplingFreq)
+	// 移動時間 [sec] = moveWidth / moveVelocity は丸めずに有理数のまま計算する
+	var moveSamples int = moveWidth * samplingRate / moveVelocity
 
 	// 0.1度動くのに必要なサンプル数
 	// [sec]*[sample/sec] / [0.1deg] = [sample/0.1deg]
 	var moveSamplesPerDeg int = moveSamples / moveWidth
 
-	// 音データの読み込み
-	sound, err := dxx.ReadFromFile(soundName)
+	return overlapAddTiming{moveSamples, moveSamplesPerDeg, moveWidth}
+}
+
+// soundLen returns the number of samples read from the sound.
+func (t overlapAddTiming) soundLen() int {
+	return t.perDeg * t.moveWidth
+}
+
+// angle returns the angle [0.1 deg] relative to the end angle of the segment i in the direction.
+func (t overlapAddTiming) angle(i int, direction string) int {
+	moveWidth := t.moveWidth
+	dataAngle := i % (moveWidth * 2)
+	if dataAngle > moveWidth {
+		dataAngle = moveWidth*2 - dataAngle
+	}
+	if direction == "cc" {
+		dataAngle = -dataAngle
+	}
+	if dataAngle < 0 {
+		dataAngle += 3600
+	}
+	return dataAngle
+}
+
+// OverlapAdd renders the moving sound of c by overlap-adding the segments of the sound convolved with the SLTF of each angle.
+// c.Method is ignored, and c is validated by Validate first.
+// If c.Resume is set, an interrupted rendering is continued instead of rendered again. See RenderConfig.Resume.
+func OverlapAdd(c RenderConfig) (RenderResult, error) {
+	c.Method = MethodOverlapAdd
+	if err := c.Validate(); err != nil {
+		return RenderResult{}, err
+	}
+	sltfs, soundName, moveWidth, moveVelocity, endAngle := c.SLTFs, c.Sound, c.MoveWidth, c.MoveVelocity, c.EndAngle
+
+	// サンプリング周波数 [sample/sec]
+	samplingRate := c.samplingRate()
+	t := newOverlapAddTiming(moveWidth, moveVelocity, samplingRate)
+	moveSamples, moveSamplesPerDeg := t.moveSamples, t.perDeg
+
+	// 音データを開く（角度ごとに必要な区間だけを読み込む）
+	sound, err := openSound(&c)
+	if err != nil {
+		return RenderResult{}, err
+	}
+	defer sound.Close()
+	soundMeta, err := dxx.ReadMeta(soundName)
 	if err != nil {
-		return err
+		return RenderResult{}, err
 	}
+	if err := checkSamplingRate(soundName, soundMeta, samplingRate); err != nil {
+		return RenderResult{}, err
+	}
+
+	SLTF, err := sltfs.Get(0, Left)
+	if err != nil {
+		return RenderResult{}, err
+	}
+
+	result := RenderResult{SamplingRate: samplingRate, MoveSamples: moveSamplesPerDeg * moveWidth}
 
-	SLTFName := fmt.Sprintf("%s/SLTF/SLTF_%d_%s.DDB", subject, 0, "L")
-	SLTF, err := dxx.ReadFromFile(SLTFName)
+	// 出力ファイル名の決定
+	outNames, err := outputNames(c.Naming, c.OutDir, OutputNameData{MoveWidth: moveWidth, MoveVelocity: moveVelocity, EndAngle: endAngle})
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	for _, direction := range []string{"c", "cc"} {
+	for _, direction := range Directions {
 		for _, LR := range []string{"L", "R"} {
-			moveOut := make([]float64, moveSamples+len(SLTF)-1)
 			usedAngles := make([]int, moveWidth)
 
-			for angle := 0; angle < moveWidth; angle++ {
-				// 畳み込むSLTFの角度を決定
-				dataAngle := angle % (moveWidth * 2)
-				if dataAngle > moveWidth {
-					dataAngle = moveWidth*2 - dataAngle
-				}
-				if direction == "cc" {
-					dataAngle = -dataAngle
-				}
-				dataAngle = dataAngle
-				if dataAngle < 0 {
-					dataAngle += 3600
-				}
-				// 使用した角度を記録（ログ出力用）
-				usedAngles[angle] = (endAngle + dataAngle) % 3600
-
-				// SLTFの読み込み
-				SLTFName := fmt.Sprintf("%s/SLTF/SLTF_%d_%s.DDB", subject, (endAngle+dataAngle)%3600, LR)
-				SLTF, err := dxx.ReadFromFile(SLTFName)
-				if err != nil {
-					return err
-				}
+			params := map[string]string{
+				"sound":         soundName,
+				"move_width":    strconv.Itoa(moveWidth),
+				"move_velocity": strconv.Itoa(moveVelocity),
+				"end_angle":     strconv.Itoa(endAngle),
+				"sampling_rate": strconv.Itoa(samplingRate),
+				"direction":     direction,
+				"ear":           LR,
+			}
+			if subject := subjectName(sltfs); subject != "" {
+				params["subject"] = subject
+			}
+			if c.PreserveSound {
+				params["preserve_sound"] = "true"
+			}
 
-				// 音データと伝達関数の畳込み
-				cutSound := sound[moveSamplesPerDeg*angle : moveSamplesPerDeg*(angle+1)]
-				soundSLTF := LinearConvolutionTimeDomain(cutSound, SLTF)
-				// Overlap-Add
-				for i, v := range soundSLTF {
-					moveOut[moveSamplesPerDeg*angle+i] += v
+			outName := outNames[direction][Ear(LR)]
+			// 再開する場合、前回の実行で出力済みのファイルは描画し直さない
+			if c.Resume {
+				if n, ok := renderedLen(outName, overlapAddCreator, params); ok {
+					result.Files = append(result.Files, outName)
+					result.Lengths = append(result.Lengths, n)
+					if _, err := fmt.Fprintf(os.Stderr, "%s: length=%d (already rendered)\n", outName, n); err != nil {
+						return result, err
+					}
+					continue
 				}
 			}
 
-			// DDBへ出力
-			outName := fmt.Sprintf("%s/move_judge_w%03d_mt%03d_%s_%d_%s.DDB", outDir, moveWidth, moveVelocity, direction, endAngle, LR)
-			if err := dxx.WriteToFile(outName, moveOut); err != nil {
-				return err
+			// DXXへ出力
+			outLen, err := writeStream(outName, outputDataType(outName), 0, params, c.Resume, func(moveOut *overlapAddWriter, start int) error {
+				for angle := 0; angle < moveWidth; angle++ {
+					// 畳み込むSLTFの角度を決定
+					dataAngle := t.angle(angle, direction)
+					// 使用した角度を記録（ログ出力用）
+					usedAngles[angle] = (endAngle + dataAngle) % 3600
+					// 中断前に出力済みの角度は飛ばす
+					if angle < start {
+						continue
+					}
+
+					// SLTFの読み込み
+					SLTF, err := sltfs.Get(Angle(endAngle+dataAngle), Ear(LR))
+					if err != nil {
+						return err
+					}
+
+					// 音データと伝達関数の畳込み
+					cutSound, err := sound.Slice(moveSamplesPerDeg*angle, moveSamplesPerDeg)
+					if err != nil {
+						return err
+					}
+					soundSLTF := convolve(cutSound, SLTF)
+					// Overlap-Add
+					moveOut.add(moveSamplesPerDeg*angle, soundSLTF)
+
+					// 以降の角度で加算されない区間を出力
+					if err := moveOut.commit(angle+1, moveSamplesPerDeg*(angle+1)); err != nil {
+						return err
+					}
+				}
+				moveOut.grow(moveSamples + len(SLTF) - 1)
+				return nil
+			})
+			if err != nil {
+				return result, err
+			}
+			meta := newRenderMeta(overlapAddCreator, samplingRate, soundMeta, params)
+			if err := dxx.WriteMeta(outName, meta); err != nil {
+				return result, err
 			}
-			_, err = fmt.Fprintf(os.Stderr, "%s: length=%d\n", outName, len(moveOut))
+			result.Files = append(result.Files, outName)
+			result.Lengths = append(result.Lengths, outLen)
+			_, err = fmt.Fprintf(os.Stderr, "%s: length=%d\n", outName, outLen)
 			if err != nil {
-				return err
+				return result, err
 			}
-			_, err := fmt.Fprintf(os.Stderr, "used angle:%v\n", usedAngles)
+			_, err = fmt.Fprintf(os.Stderr, "used angle:%v\n", usedAngles)
 			if err != nil {
-				return err
+				return result, err
 			}
 		}
 	}
-	return nil
+	return result, nil
 }
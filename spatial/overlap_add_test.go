@@ -0,0 +1,89 @@
+package spatial
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+func TestOverlapAddTiming(t *testing.T) {
+	for _, c := range []struct {
+		moveWidth, moveVelocity, samplingRate int
+		// moveSamples is moveWidth*samplingRate/moveVelocity rounded down, and perDeg is moveSamples/moveWidth rounded down
+		moveSamples, perDeg, soundLen int
+	}{
+		{40, 40, 8000, 8000, 200, 8000},
+		// 18666.67 samples, 2666.67 per angle
+		{7, 3, 8000, 18666, 2666, 18662},
+		// 3.333... s is 160000 samples, not 3333 ms = 159984 samples
+		{10, 3, 48000, 160000, 16000, 160000},
+		// 205714.29 samples, 6857.14 per angle
+		{30, 7, 48000, 205714, 6857, 205710},
+	} {
+		tm := newOverlapAddTiming(c.moveWidth, c.moveVelocity, c.samplingRate)
+		if tm.moveSamples != c.moveSamples || tm.perDeg != c.perDeg || tm.soundLen() != c.soundLen {
+			t.Errorf("width %d velocity %d at %d Hz: moveSamples, perDeg, soundLen() = %d, %d, %d, want %d, %d, %d",
+				c.moveWidth, c.moveVelocity, c.samplingRate, tm.moveSamples, tm.perDeg, tm.soundLen(), c.moveSamples, c.perDeg, c.soundLen)
+		}
+	}
+
+	// the angle moves 0.1 deg every segment
+	tm := newOverlapAddTiming(4, 1, 48000)
+	for _, c := range []struct {
+		direction string
+		want      []int
+	}{
+		{"c", []int{0, 1, 2, 3}},
+		{"cc", []int{0, 3599, 3598, 3597}},
+	} {
+		for i, want := range c.want {
+			if got := tm.angle(i, c.direction); got != want {
+				t.Errorf("angle(%d, %s) = %d, want %d", i, c.direction, got, want)
+			}
+		}
+	}
+}
+
+// TestOverlapAddLength checks the reported movement and the lengths of the files when the samples per angle are not whole.
+func TestOverlapAddLength(t *testing.T) {
+	discardStderr(t)
+	c := testRendering(t, MethodOverlapAdd)
+	c.MoveWidth, c.MoveVelocity = 7, 3
+	result, err := Render(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 7 whole segments of 2666 samples
+	if result.MoveSamples != 18662 {
+		t.Errorf("MoveSamples = %d, want 18662", result.MoveSamples)
+	}
+	// the files span the requested 18666 samples and the SLTF of 64 samples,
+	// so the last segment is followed by 4 silent samples
+	for i, n := range result.Lengths {
+		if want := 18666 + 64 - 1; n != want {
+			t.Errorf("%s: %d samples, want %d", result.Files[i], n, want)
+		}
+		data, err := dxx.ReadFromFile(result.Files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data[18662+64-2] == 0 {
+			t.Errorf("%s: the last sample of the last segment is 0", result.Files[i])
+		}
+		for j, v := range data[18662+64-1:] {
+			if v != 0 {
+				t.Errorf("%s: sample %d after the last segment is %v", result.Files[i], 18662+64-1+j, v)
+			}
+		}
+	}
+
+	if err := dxx.WriteFileWithMeta(c.Sound, randomSignal(rand.New(rand.NewSource(3)), 18661), &dxx.Meta{SamplingRate: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	var tooShort ErrSoundTooShort
+	if err := c.Validate(); !errors.As(err, &tooShort) || tooShort.Need != 18662 {
+		t.Errorf("Validate of a sound of 18661 samples: got %v, want ErrSoundTooShort of 18662 samples", err)
+	}
+}
@@ -0,0 +1,365 @@
+package spatial
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// Angle is an azimuth in 0.1 degree.
+type Angle int
+
+// Norm returns the angle in [0, 3600).
+func (a Angle) Norm() Angle {
+	return (a%3600 + 3600) % 3600
+}
+
+// Ear is the ear of an SLTF, "L" or "R".
+type Ear string
+
+const (
+	Left  Ear = "L"
+	Right Ear = "R"
+)
+
+// Ears are the ears in the order the renderers render them.
+var Ears = []Ear{Left, Right}
+
+// SLTFSet is a set of SLTFs of a subject.
+// The SLTFs keep their levels, so that the differences between the ears and between the angles are rendered.
+// The implementations are safe for concurrent use.
+type SLTFSet interface {
+	// Get returns the SLTF of the angle and the ear. The angle is taken modulo 3600.
+	// The returned slice may be shared and must not be modified.
+	// If the set has no such SLTF, the error satisfies errors.Is(err, fs.ErrNotExist).
+	Get(angle Angle, ear Ear) ([]float64, error)
+	// Angles returns the angles of the SLTFs in ascending order.
+	Angles() []Angle
+	// Len returns the number of SLTFs, counting each ear.
+	Len() int
+}
+
+// subjectName returns the name of the subject of the set recorded in the metadata, or "" if unknown.
+func subjectName(set SLTFSet) string {
+	if s, ok := set.(interface{ Subject() string }); ok {
+		return s.Subject()
+	}
+	return ""
+}
+
+// sltfKey identifies an SLTF in a set.
+type sltfKey struct {
+	angle Angle
+	ear   Ear
+}
+
+// sortedAngles returns the angles of the keys in ascending order.
+func sortedAngles(keys map[sltfKey]bool) []Angle {
+	seen := make(map[Angle]bool)
+	var angles []Angle
+	for k := range keys {
+		if !seen[k.angle] {
+			seen[k.angle] = true
+			angles = append(angles, k.angle)
+		}
+	}
+	sort.Slice(angles, func(i, j int) bool { return angles[i] < angles[j] })
+	return angles
+}
+
+// SLTFName returns the name of the SLTF of the angle [0.1 deg] and the ear ("L" or "R") in a subject directory
+// by DefaultSLTFNaming. The angle is taken modulo 3600.
+// Use SLTFNaming.Name for other namings.
+func SLTFName(angle int, ear string) string {
+	// DefaultSLTFNaming always makes a valid name.
+	name, _ := defaultSLTFNaming.Name(Angle(angle), Ear(ear))
+	return name
+}
+
+// ReadSLTF reads the SLTF of the angle [0.1 deg] and the ear ("L" or "R") and its metadata from a subject directory
+// named by DefaultSLTFNaming.
+// subject is the file system rooted at the subject directory, e.g. os.DirFS("path/to/subject"),
+// an embed.FS, a *zip.Reader or an fstest.MapFS.
+// The stored values are kept, unlike dxx.ReadFromFile, which normalises DSX and DFX data by its peak.
+// Use NewSLTFDir for other namings.
+func ReadSLTF(subject fs.FS, angle int, ear string) ([]float64, *dxx.Meta, error) {
+	return readSLTF(subject, SLTFName(angle, ear))
+}
+
+// readSLTF reads the SLTF file name in fsys and its metadata, keeping the stored values.
+func readSLTF(fsys fs.FS, name string) ([]float64, *dxx.Meta, error) {
+	// a Scaler of its own for each read, since Scaler counts the clipped samples.
+	return dxx.ReadFileWithMetaFSWithOptions(fsys, name, &dxx.Options{Scaler: &conv.Scaler{}})
+}
+
+// sltfNotExist returns the error of an SLTF which is not in a set. name is the name of its file, or "" if it has none.
+func sltfNotExist(op, name string, angle Angle, ear Ear) error {
+	if name == "" {
+		name = fmt.Sprintf("SLTF of angle %d ear %s", angle.Norm(), ear)
+	}
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// SLTFDir is an SLTFSet of the SLTF files in a subject directory, which are read on every Get with their stored values.
+type SLTFDir struct {
+	fsys         fs.FS
+	subject      string
+	naming       *SLTFNaming
+	samplingRate int
+	// names are the names of the existing SLTF files.
+	names  map[sltfKey]string
+	angles []Angle
+}
+
+// OpenSLTFDir returns the SLTFSet of the subject directory.
+// See NewSLTFDir for naming and samplingRate.
+func OpenSLTFDir(subject string, naming *SLTFNaming, samplingRate int) (*SLTFDir, error) {
+	d, err := NewSLTFDir(os.DirFS(subject), naming, samplingRate)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", subject, err)
+	}
+	d.subject = subject
+	return d, nil
+}
+
+// NewSLTFDir returns the SLTFSet of the subject file system, e.g. an embed.FS or a *zip.Reader
+// whose root is the subject directory.
+// The SLTF files are named by naming, or as SLTFName if naming is nil, and the set has the files which exist.
+// If samplingRate is not 0, Get fails for an SLTF whose metadata declares another sampling rate [Hz].
+func NewSLTFDir(subject fs.FS, naming *SLTFNaming, samplingRate int) (*SLTFDir, error) {
+	if naming == nil {
+		naming = defaultSLTFNaming
+	}
+	d := &SLTFDir{fsys: subject, naming: naming, samplingRate: samplingRate, names: make(map[sltfKey]string)}
+	// the names of the files in each directory of the SLTFs
+	dirs := make(map[string]map[string]bool)
+	for angle := Angle(0); angle < 3600; angle++ {
+		for _, ear := range Ears {
+			name, err := naming.Name(angle, ear)
+			if err != nil {
+				return nil, err
+			}
+			dir := path.Dir(name)
+			files, ok := dirs[dir]
+			if !ok {
+				if files, err = listFiles(subject, dir); err != nil {
+					return nil, err
+				}
+				dirs[dir] = files
+			}
+			if files[path.Base(name)] {
+				d.names[sltfKey{angle, ear}] = name
+			}
+		}
+	}
+	if len(d.names) == 0 {
+		return nil, fmt.Errorf("no SLTF files named like %s: %w", naming.t.Root.String(), fs.ErrNotExist)
+	}
+	keys := make(map[sltfKey]bool, len(d.names))
+	for k := range d.names {
+		keys[k] = true
+	}
+	d.angles = sortedAngles(keys)
+	return d, nil
+}
+
+// listFiles returns the names of the files in the directory. A directory which does not exist has no files.
+func listFiles(fsys fs.FS, dir string) (map[string]bool, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			files[e.Name()] = true
+		}
+	}
+	return files, nil
+}
+
+// Get reads the SLTF of the angle and the ear.
+func (d *SLTFDir) Get(angle Angle, ear Ear) ([]float64, error) {
+	angle = angle.Norm()
+	name, ok := d.names[sltfKey{angle, ear}]
+	if !ok {
+		// the name which was looked up, if the naming makes one
+		name, _ = d.naming.Name(angle, ear)
+		return nil, sltfNotExist("open", name, angle, ear)
+	}
+	data, meta, err := readSLTF(d.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if d.samplingRate != 0 {
+		if err := checkSamplingRate(name, meta, d.samplingRate); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Angles returns the angles of the SLTFs in ascending order.
+func (d *SLTFDir) Angles() []Angle {
+	return append([]Angle(nil), d.angles...)
+}
+
+// Len returns the number of SLTFs, counting each ear.
+func (d *SLTFDir) Len() int {
+	return len(d.names)
+}
+
+// Subject returns the subject directory given to OpenSLTFDir, or "".
+func (d *SLTFDir) Subject() string {
+	return d.subject
+}
+
+// SLTFMem is an SLTFSet held in memory.
+type SLTFMem struct {
+	subject string
+	m       map[sltfKey][]float64
+	angles  []Angle
+}
+
+// NewSLTFMem returns an empty SLTFSet in memory. SLTFs are added by Add.
+func NewSLTFMem() *SLTFMem {
+	return &SLTFMem{m: make(map[sltfKey][]float64)}
+}
+
+// Preload reads all the SLTFs of the set into memory.
+func Preload(set SLTFSet) (*SLTFMem, error) {
+	m := NewSLTFMem()
+	m.subject = subjectName(set)
+	for _, angle := range set.Angles() {
+		for _, ear := range Ears {
+			data, err := set.Get(angle, ear)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			m.Add(angle, ear, data)
+		}
+	}
+	return m, nil
+}
+
+// Add adds the SLTF of the angle and the ear, replacing any SLTF of them.
+// It must not be called concurrently with the other methods.
+func (m *SLTFMem) Add(angle Angle, ear Ear, data []float64) {
+	angle = angle.Norm()
+	i := sort.Search(len(m.angles), func(i int) bool { return m.angles[i] >= angle })
+	if i == len(m.angles) || m.angles[i] != angle {
+		m.angles = append(m.angles, 0)
+		copy(m.angles[i+1:], m.angles[i:])
+		m.angles[i] = angle
+	}
+	m.m[sltfKey{angle, ear}] = data
+}
+
+// Get returns the SLTF of the angle and the ear.
+func (m *SLTFMem) Get(angle Angle, ear Ear) ([]float64, error) {
+	data, ok := m.m[sltfKey{angle.Norm(), ear}]
+	if !ok {
+		return nil, sltfNotExist("get", "", angle, ear)
+	}
+	return data, nil
+}
+
+// Angles returns the angles of the SLTFs in ascending order.
+func (m *SLTFMem) Angles() []Angle {
+	return append([]Angle(nil), m.angles...)
+}
+
+// Len returns the number of SLTFs, counting each ear.
+func (m *SLTFMem) Len() int {
+	return len(m.m)
+}
+
+// Subject returns the subject of the preloaded set, or "".
+func (m *SLTFMem) Subject() string {
+	return m.subject
+}
+
+// SLTFCache is an SLTFSet which keeps the recently used SLTFs of another set in memory.
+type SLTFCache struct {
+	set  SLTFSet
+	size int
+
+	mu sync.Mutex
+	// ll holds the cached entries from the most recently used.
+	ll *list.List
+	m  map[sltfKey]*list.Element
+}
+
+type cacheEntry struct {
+	key  sltfKey
+	data []float64
+}
+
+// NewSLTFCache returns the set which caches up to size SLTFs of set, discarding the least recently used.
+func NewSLTFCache(set SLTFSet, size int) *SLTFCache {
+	if size < 1 {
+		size = 1
+	}
+	return &SLTFCache{set: set, size: size, ll: list.New(), m: make(map[sltfKey]*list.Element)}
+}
+
+// Get returns the SLTF of the angle and the ear, from the cache if possible.
+func (c *SLTFCache) Get(angle Angle, ear Ear) ([]float64, error) {
+	k := sltfKey{angle.Norm(), ear}
+	c.mu.Lock()
+	if e, ok := c.m[k]; ok {
+		c.ll.MoveToFront(e)
+		c.mu.Unlock()
+		return e.Value.(*cacheEntry).data, nil
+	}
+	c.mu.Unlock()
+
+	data, err := c.set.Get(angle, ear)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.m[k]; ok {
+		// read concurrently by another Get.
+		c.ll.MoveToFront(e)
+		return e.Value.(*cacheEntry).data, nil
+	}
+	c.m[k] = c.ll.PushFront(&cacheEntry{k, data})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.m, e.Value.(*cacheEntry).key)
+	}
+	return data, nil
+}
+
+// Angles returns the angles of the SLTFs in ascending order.
+func (c *SLTFCache) Angles() []Angle {
+	return c.set.Angles()
+}
+
+// Len returns the number of SLTFs, counting each ear.
+func (c *SLTFCache) Len() int {
+	return c.set.Len()
+}
+
+// Subject returns the subject of the cached set, or "".
+func (c *SLTFCache) Subject() string {
+	return subjectName(c.set)
+}
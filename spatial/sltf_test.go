@@ -0,0 +1,403 @@
+package spatial
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// countingSet counts the SLTFs got from the set.
+type countingSet struct {
+	SLTFSet
+	mu   sync.Mutex
+	gets map[sltfKey]int
+}
+
+func newCountingSet(set SLTFSet) *countingSet {
+	return &countingSet{SLTFSet: set, gets: make(map[sltfKey]int)}
+}
+
+func (s *countingSet) Get(angle Angle, ear Ear) ([]float64, error) {
+	s.mu.Lock()
+	s.gets[sltfKey{angle.Norm(), ear}]++
+	s.mu.Unlock()
+	return s.SLTFSet.Get(angle, ear)
+}
+
+func (s *countingSet) count(angle Angle, ear Ear) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets[sltfKey{angle.Norm(), ear}]
+}
+
+// testSLTFs returns SLTFs of 4 samples at the angles, whose first sample is the angle and second the ear.
+func testSLTFs(angles ...Angle) *SLTFMem {
+	m := NewSLTFMem()
+	for _, angle := range angles {
+		for i, ear := range Ears {
+			m.Add(angle, ear, []float64{float64(angle), float64(i), 0, 0})
+		}
+	}
+	return m
+}
+
+func TestSLTFCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	set := newCountingSet(testSLTFs(0, 10, 20, 30))
+	c := NewSLTFCache(set, 3)
+	get := func(angle Angle) {
+		t.Helper()
+		data, err := c.Get(angle, Left)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data[0] != float64(angle) {
+			t.Fatalf("Get(%d) = %v", angle, data)
+		}
+	}
+	get(0)
+	get(10)
+	get(20)
+	// 0 is used again, so 10 is the least recently used
+	get(0)
+	get(30)
+	for _, c := range []struct {
+		angle Angle
+		want  int
+	}{{0, 1}, {10, 1}, {20, 1}, {30, 1}} {
+		if got := set.count(c.angle, Left); got != c.want {
+			t.Errorf("angle %d read %d times, want %d", c.angle, got, c.want)
+		}
+	}
+	// from the most recently used: 30, 0, 20
+	get(20)
+	get(0)
+	get(30)
+	get(10)
+	if got := set.count(10, Left); got != 2 {
+		t.Errorf("evicted angle 10 read %d times, want 2", got)
+	}
+	// 10 evicted 20, the least recently used of 20, 0 and 30
+	get(0)
+	get(30)
+	get(20)
+	if got := set.count(20, Left); got != 2 {
+		t.Errorf("angle 20 read %d times, want 2", got)
+	}
+	for _, angle := range []Angle{0, 30} {
+		if got := set.count(angle, Left); got != 1 {
+			t.Errorf("angle %d read %d times, want 1", angle, got)
+		}
+	}
+
+	// the ears and equal angles modulo 3600 share entries
+	if _, err := c.Get(3600+20, Left); err != nil {
+		t.Fatal(err)
+	}
+	if got := set.count(20, Left); got != 2 {
+		t.Errorf("angle 3620 read angle 20 again: %d times", got)
+	}
+	data, err := c.Get(20, Right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[1] != 1 {
+		t.Errorf("Get(20, R) = %v, the SLTF of the left ear", data)
+	}
+
+	// errors are not cached
+	if _, err := c.Get(40, Left); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get of a missing SLTF: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := c.Get(40, Left); !errors.Is(err, fs.ErrNotExist) || set.count(40, Left) != 2 {
+		t.Errorf("Get of a missing SLTF again: got %v after %d reads", err, set.count(40, Left))
+	}
+}
+
+// TestSLTFCacheConcurrentGet checks the locking of the cache when run with -race.
+func TestSLTFCacheConcurrentGet(t *testing.T) {
+	angles := make([]Angle, 50)
+	for i := range angles {
+		angles[i] = Angle(i * 10)
+	}
+	set := newCountingSet(testSLTFs(angles...))
+	c := NewSLTFCache(set, 20)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				angle, ear := angles[(i*7+g*13)%len(angles)], Ears[(i+g)%2]
+				data, err := c.Get(angle, ear)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if data[0] != float64(angle) || Ears[int(data[1])] != ear {
+					errs <- fmt.Errorf("Get(%d, %s) = %v", angle, ear, data)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := c.ll.Len(); n > 20 || n != len(c.m) {
+		t.Errorf("%d entries in the list and %d in the map, want at most 20 of each", n, len(c.m))
+	}
+}
+
+// sltfLevel is the level of the test SLTF of the angle and the ear: the right ear is 12 dB below the left,
+// and the odd angles 6 dB below the even ones.
+func sltfLevel(angle Angle, ear Ear) float64 {
+	level := 1.0
+	if ear == Right {
+		level /= 4
+	}
+	if angle%2 == 1 {
+		level /= 2
+	}
+	return level
+}
+
+// levelSLTFs returns the file system of a subject with SLTFs of every angle named by the naming,
+// whose samples are scale*sltfLevel(angle, ear)*[1, 0.5, 0], stored as the data type of the extension.
+func levelSLTFs(t *testing.T, naming string, scale float64) fstest.MapFS {
+	t.Helper()
+	n, err := ParseSLTFNaming(naming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := make(fstest.MapFS)
+	for angle := Angle(0); angle < 3600; angle++ {
+		for _, ear := range Ears {
+			name, err := n.Name(angle, ear)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dt, err := dxx.StringToDataType(strings.TrimPrefix(path.Ext(name), "."))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := scale * sltfLevel(angle, ear)
+			var buf bytes.Buffer
+			if err := dxx.WriteWithOptions(&buf, dt, []float64{v, v / 2, 0}, &dxx.Options{Scaler: &conv.Scaler{}}); err != nil {
+				t.Fatal(err)
+			}
+			fsys[name] = &fstest.MapFile{Data: buf.Bytes()}
+		}
+	}
+	return fsys
+}
+
+// TestSLTFDirKeepsLevels checks that DSB and DFB SLTFs are not normalised each on its own.
+func TestSLTFDirKeepsLevels(t *testing.T) {
+	for _, c := range []struct {
+		naming string
+		scale  float64
+	}{
+		{`SLTF/SLTF_{{.Angle}}_{{.Ear}}.DSB`, 800},
+		{`HRIR/az{{printf "%04d" .Angle}}_{{.Ear}}.DFB`, 0.02},
+		{`SLTF/SLTF_{{.Angle}}_{{.Ear}}.DDB`, 0.02},
+	} {
+		naming, err := ParseSLTFNaming(c.naming)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := NewSLTFDir(levelSLTFs(t, c.naming, c.scale), naming, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, angle := range []Angle{0, 1, 3599} {
+			for _, ear := range Ears {
+				got, err := d.Get(angle, ear)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// the values stored in float32 for DFB
+				want := float64(float32(c.scale * sltfLevel(angle, ear)))
+				if strings.HasSuffix(c.naming, ".DDB") {
+					want = c.scale * sltfLevel(angle, ear)
+				}
+				if got[0] != want || got[1] != want/2 || got[2] != 0 {
+					t.Errorf("%s: Get(%d, %s) = %v, want [%v %v 0]", c.naming, angle, ear, got, want, want/2)
+				}
+			}
+		}
+	}
+}
+
+// TestRenderKeepsSLTFLevels checks that the rendered ears differ by the level difference of DFB SLTFs.
+func TestRenderKeepsSLTFLevels(t *testing.T) {
+	discardStderr(t)
+	const naming = `HRIR/az{{printf "%04d" .Angle}}_{{.Ear}}.DFB`
+	sltfNaming, err := ParseSLTFNaming(naming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sltfs, err := NewSLTFDir(levelSLTFs(t, naming, 0.5), sltfNaming, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		c := testRendering(t, method)
+		c.SLTFs = sltfs
+		// the even end angle is an even angle at every segment, since the movement steps by 1 deg
+		c.EndAngle = 3500
+		result, err := Render(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(result.Files); i += 2 {
+			l, err := dxx.ReadFromFile(result.Files[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := dxx.ReadFromFile(result.Files[i+1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := range l {
+				if math.Abs(l[j]-4*r[j]) > 1e-9*math.Abs(l[j]) {
+					t.Fatalf("%s: sample %d of %s is %v, want 4 times %v of %s", method, j, result.Files[i], l[j], r[j], result.Files[i+1])
+				}
+			}
+		}
+	}
+}
+
+// TestRenderReadsCachedSLTFsOnce checks that Render, which validates the SLTFs before rendering with them, reads each SLTF once through a cache.
+func TestRenderReadsCachedSLTFsOnce(t *testing.T) {
+	discardStderr(t)
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		c := testRendering(t, method)
+		set := newCountingSet(c.SLTFs)
+		c.SLTFs = NewSLTFCache(set, 3600*len(Ears))
+		if _, err := Render(c); err != nil {
+			t.Fatal(err)
+		}
+		set.mu.Lock()
+		for k, n := range set.gets {
+			if n != 1 {
+				t.Errorf("%s: SLTF at %d %v read %d times, want 1", method, k.angle, k.ear, n)
+			}
+		}
+		set.mu.Unlock()
+	}
+}
+
+// ddb returns data stored as DDB.
+func ddb(t *testing.T, data []float64) *fstest.MapFile {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := dxx.Write(&buf, dxx.DDB, data); err != nil {
+		t.Fatal(err)
+	}
+	return &fstest.MapFile{Data: buf.Bytes()}
+}
+
+func TestReadSLTF(t *testing.T) {
+	subject := fstest.MapFS{
+		"SLTF/SLTF_0_L.DDB":               ddb(t, []float64{0.5, 0.25}),
+		"SLTF/SLTF_0_L.DDB" + dxx.MetaExt: {Data: []byte(`{"sampling_rate": 48000}`)},
+		"SLTF/SLTF_10_R.DDB":              ddb(t, []float64{-1}),
+	}
+	data, meta, err := ReadSLTF(subject, 0, "L")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 || data[0] != 0.5 || data[1] != 0.25 || meta == nil || meta.SamplingRate != 48000 {
+		t.Errorf("ReadSLTF(0, L) = %v, %+v", data, meta)
+	}
+	// the angle is taken modulo 3600
+	data, meta, err = ReadSLTF(subject, 3610, "R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0] != -1 || meta != nil {
+		t.Errorf("ReadSLTF(3610, R) = %v, %+v", data, meta)
+	}
+	if _, _, err := ReadSLTF(subject, 10, "L"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadSLTF(10, L): got %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestNewSLTFDir(t *testing.T) {
+	subject := fstest.MapFS{
+		"SLTF/SLTF_0_L.DDB":                  ddb(t, []float64{1}),
+		"SLTF/SLTF_0_R.DDB":                  ddb(t, []float64{2}),
+		"SLTF/SLTF_900_L.DDB":                ddb(t, []float64{3}),
+		"SLTF/SLTF_900_L.DDB" + dxx.MetaExt:  {Data: []byte(`{"sampling_rate": 44100}`)},
+		"SLTF/SLTF_1800_R.DDB":               ddb(t, []float64{4}),
+		"SLTF/SLTF_1800_R.DDB" + dxx.MetaExt: {Data: []byte(`{"sampling_rate": 48000}`)},
+		// not SLTFs of the naming
+		"SLTF/SLTF_3600_L.DDB": ddb(t, []float64{5}),
+		"SLTF/readme.txt":      {Data: []byte("SLTFs")},
+		"other/SLTF_0_L.DDB":   ddb(t, []float64{6}),
+	}
+	d, err := NewSLTFDir(subject, nil, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Angles(), []Angle{0, 900, 1800}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Angles() = %v, want %v", got, want)
+	}
+	if d.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", d.Len())
+	}
+	for _, c := range []struct {
+		angle Angle
+		ear   Ear
+		want  float64
+	}{{0, Left, 1}, {3600, Right, 2}, {1800, Right, 4}} {
+		got, err := d.Get(c.angle, c.ear)
+		if err != nil {
+			t.Fatalf("Get(%d, %s): %v", c.angle, c.ear, err)
+		}
+		if len(got) != 1 || got[0] != c.want {
+			t.Errorf("Get(%d, %s) = %v, want [%v]", c.angle, c.ear, got, c.want)
+		}
+	}
+	if _, err := d.Get(900, Left); !errors.Is(err, dxx.ErrSamplingRateMismatch) || !strings.Contains(err.Error(), "SLTF_900_L.DDB") {
+		t.Errorf("Get(900, L) of 44100 Hz: got %v, want ErrSamplingRateMismatch of the file", err)
+	}
+	var pe *fs.PathError
+	if _, err := d.Get(900, Right); !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &pe) || pe.Path != "SLTF/SLTF_900_R.DDB" {
+		t.Errorf("Get(900, R): got %v, want fs.ErrNotExist of SLTF/SLTF_900_R.DDB", err)
+	}
+
+	// without a sampling rate, the metadata is not checked
+	d, err = NewSLTFDir(subject, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(900, Left); err != nil {
+		t.Errorf("Get(900, L) without a sampling rate: %v", err)
+	}
+
+	naming, err := ParseSLTFNaming(`other/SLTF_{{.Angle}}_{{.Ear}}.DDB`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err = NewSLTFDir(subject, naming, 0); err != nil || d.Len() != 1 {
+		t.Fatalf("NewSLTFDir of other/: got %v, want 1 SLTF", err)
+	}
+	if _, err := NewSLTFDir(fstest.MapFS{"readme.txt": {}}, nil, 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("NewSLTFDir without SLTFs: got %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,66 @@
+package spatial
+
+import (
+	"io"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// soundAmplitude is the peak absolute value DSX and DFX sounds are normalised to, as by dxx.ReadFromFile.
+const soundAmplitude = 10000.0
+
+// soundFile is the sound source of a rendering, whose segments are read as they are rendered.
+type soundFile struct {
+	f *dxx.File
+	// peak is the peak absolute value the samples are normalised by, or 0 if they are kept.
+	peak float64
+}
+
+// openSound opens the sound of c.
+// Unless c.PreserveSound is set, DSX and DFX sounds are normalised by the peak of the whole sound,
+// so that the segments have the values dxx.ReadFromFile reads.
+func openSound(c *RenderConfig) (*soundFile, error) {
+	f, err := dxx.Open(c.Sound)
+	if err != nil {
+		return nil, err
+	}
+	s := &soundFile{f: f}
+	if c.PreserveSound || f.DataType() == dxx.DDA || f.DataType() == dxx.DDB {
+		return s, nil
+	}
+	buf := make([]float64, 4096)
+	for off := 0; off < f.Len(); off += len(buf) {
+		n, err := f.ReadAt(buf, int64(off))
+		if err != nil && err != io.EOF {
+			f.Close()
+			return nil, err
+		}
+		if p := conv.MaxAbs(buf[:n]); p > s.peak {
+			s.peak = p
+		}
+	}
+	return s, nil
+}
+
+// Len returns the number of samples of the sound.
+func (s *soundFile) Len() int {
+	return s.f.Len()
+}
+
+// Slice returns n samples starting at sample start like dxx.File.Slice, normalised unless they are kept.
+func (s *soundFile) Slice(start, n int) ([]float64, error) {
+	data, err := s.f.Slice(start, n)
+	if err != nil || s.peak == 0 {
+		return data, err
+	}
+	for i, v := range data {
+		data[i] = v / s.peak * soundAmplitude
+	}
+	return data, nil
+}
+
+// Close closes the sound file.
+func (s *soundFile) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,103 @@
+package spatial
+
+import (
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetsuzawa/go-soundlib/conv"
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// TestRenderDSBSoundLevel checks that a DSB sound is rendered normalised to a peak of 10000 as dxx.ReadFromFile reads it,
+// and with its stored values if PreserveSound is set.
+func TestRenderDSBSoundLevel(t *testing.T) {
+	discardStderr(t)
+	rng := rand.New(rand.NewSource(2))
+	stored := make([]float64, 20000)
+	for i := range stored {
+		stored[i] = float64(rng.Intn(4001) - 2000)
+	}
+	stored[100] = 2500
+	normalised := make([]float64, len(stored))
+	for i, v := range stored {
+		normalised[i] = v / 2500 * 10000
+	}
+
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		c := testRendering(t, method)
+		dir := filepath.Dir(c.Sound)
+		sound := filepath.Join(dir, "sound.DSB")
+		if err := dxx.WriteToFileWithOptions(sound, stored, &dxx.Options{Scaler: &conv.Scaler{}}); err != nil {
+			t.Fatal(err)
+		}
+		read, err := dxx.ReadFromFile(sound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSameSamples(t, "dxx.ReadFromFile", read, normalised)
+
+		for _, preserve := range []bool{false, true} {
+			want := normalised
+			if preserve {
+				want = stored
+			}
+			// the same samples in a DDB sound, which is kept
+			ref := c
+			ref.Sound = filepath.Join(t.TempDir(), "sound.DDB")
+			ref.OutDir = filepath.Join(t.TempDir(), "out")
+			if err := dxx.WriteToFile(ref.Sound, want); err != nil {
+				t.Fatal(err)
+			}
+			wantResult, err := Render(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := c
+			got.Sound, got.PreserveSound = sound, preserve
+			got.OutDir = filepath.Join(t.TempDir(), "out")
+			gotResult, err := Render(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, name := range gotResult.Files {
+				if !sameContent(t, name, wantResult.Files[i]) {
+					t.Errorf("%s with PreserveSound %v: %s differs from the rendering of the DDB sound", method, preserve, filepath.Base(name))
+				}
+			}
+		}
+	}
+}
+
+func assertSameSamples(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d samples, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s: sample %d is %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// TestRenderWAVSound checks that a WAV sound is rejected before any SLTF is read.
+func TestRenderWAVSound(t *testing.T) {
+	for _, method := range []Method{MethodFadeinFadeout, MethodOverlapAdd} {
+		c := testRendering(t, method)
+		c.Sound = filepath.Join(t.TempDir(), "sound.wav")
+		if err := dxx.WriteFileWithMeta(c.Sound, randomSignal(rand.New(rand.NewSource(1)), 20000), &dxx.Meta{SamplingRate: 8000}); err != nil {
+			t.Fatal(err)
+		}
+		sltfs := newCountingSet(c.SLTFs)
+		c.SLTFs = sltfs
+		if _, err := Render(c); !errors.Is(err, dxx.ErrOpenWAV) {
+			t.Errorf("%s: got %v, want dxx.ErrOpenWAV", method, err)
+		}
+		if n := len(sltfs.gets); n != 0 {
+			t.Errorf("%s: %d SLTFs read", method, n)
+		}
+	}
+}
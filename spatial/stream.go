@@ -0,0 +1,214 @@
+package spatial
+
+import (
+	"os"
+	"time"
+
+	"github.com/tetsuzawa/go-soundlib/dxx"
+)
+
+// overlapAddWriter accumulates overlapping segments and streams the samples to w
+// as soon as no later segment can modify them.
+type overlapAddWriter struct {
+	w *dxx.AppendFile
+	// number of leading samples to drop from the output
+	skip int
+	// samples from off to off+len(buf) which are not written yet
+	buf []float64
+	off int
+	// number of samples written to w
+	n int
+
+	// checkpoint of the rendering, saved by commit, or nil for ASCII outputs, which are not resumed
+	ckpt      *checkpoint
+	name      string
+	lastSaved time.Time
+	// saved reports whether there is a checkpoint to resume from.
+	saved bool
+}
+
+// len returns the total length of the output including the samples already written.
+func (o *overlapAddWriter) len() int {
+	return o.off + len(o.buf)
+}
+
+// grow extends the output with zeros up to length n.
+func (o *overlapAddWriter) grow(n int) {
+	if n > o.len() {
+		o.buf = append(o.buf, make([]float64, n-o.len())...)
+	}
+}
+
+// add adds x to the output starting at sample pos.
+// pos must not precede the samples already written.
+func (o *overlapAddWriter) add(pos int, x []float64) {
+	o.grow(pos + len(x))
+	for i, v := range x {
+		o.buf[pos-o.off+i] += v
+	}
+}
+
+// flush writes the samples before pos to w.
+func (o *overlapAddWriter) flush(pos int) error {
+	if pos > o.len() {
+		pos = o.len()
+	}
+	k := pos - o.off
+	if k <= 0 {
+		return nil
+	}
+	out := o.buf[:k]
+	if o.skip > 0 {
+		s := o.skip
+		if s > len(out) {
+			s = len(out)
+		}
+		out = out[s:]
+		o.skip -= s
+	}
+	n, err := o.w.WriteSamples(out)
+	o.n += n
+	if err != nil {
+		return err
+	}
+	o.buf = append(o.buf[:0], o.buf[k:]...)
+	o.off = pos
+	return nil
+}
+
+// commit flushes the samples before pos and records that the segments before next are rendered.
+// At most every checkpointInterval, the written samples of binary outputs are synced and a checkpoint is saved,
+// from which an interrupted rendering is resumed.
+func (o *overlapAddWriter) commit(next, pos int) error {
+	if err := o.flush(pos); err != nil {
+		return err
+	}
+	if o.ckpt == nil || time.Since(o.lastSaved) < checkpointInterval {
+		return nil
+	}
+	if err := o.w.Sync(); err != nil {
+		return err
+	}
+	o.ckpt.Next, o.ckpt.Written, o.ckpt.Skip, o.ckpt.Off = next, o.n, o.skip, o.off
+	o.ckpt.setPending(o.buf)
+	if err := o.ckpt.save(o.name); err != nil {
+		return err
+	}
+	o.lastSaved, o.saved = time.Now(), true
+	return nil
+}
+
+// writeStream creates the file and streams the samples rendered by fn to it as specified data type.
+// The first skip samples of the rendered signal are dropped.
+// fn renders the segments from start, calling commit after each segment.
+// The samples are written to a part file next to the file, which is renamed to the file when the whole signal is written.
+// If the rendering of binary data is interrupted, the part file and the last checkpoint are kept,
+// and a rendering of the same params with resume set continues from the checkpoint instead of rendering the completed segments again.
+// ASCII data has no fixed sample size to cut the part file at, so it is always rendered from the start.
+// It returns the number of samples written.
+func writeStream(filename string, dt dxx.DataType, skip int, params map[string]string, resume bool, fn func(o *overlapAddWriter, start int) error) (int, error) {
+	o, start, err := openStream(filename, dt, skip, params, resume)
+	if err != nil {
+		return 0, err
+	}
+	done := false
+	defer func() {
+		if done {
+			return
+		}
+		o.w.Close()
+		if !o.saved {
+			// nothing to resume from.
+			removeCheckpoint(filename)
+		}
+	}()
+
+	if err := fn(o, start); err != nil {
+		return o.n, err
+	}
+	if err := o.flush(o.len()); err != nil {
+		return o.n, err
+	}
+	if err := o.w.Sync(); err != nil {
+		return o.n, err
+	}
+	done = true
+	if err := o.w.Close(); err != nil {
+		return o.n, err
+	}
+	if err := os.Rename(partName(filename), filename); err != nil {
+		return o.n, err
+	}
+	return o.n, removeCheckpoint(filename)
+}
+
+// openStream opens the part file of the output, resuming from its checkpoint if resume is set and there is one.
+// It returns the index of the first segment to render.
+func openStream(filename string, dt dxx.DataType, skip int, params map[string]string, resume bool) (*overlapAddWriter, int, error) {
+	if resume && dt.IsBinary() {
+		c, err := loadCheckpoint(filename, dt, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		if c != nil {
+			if o, err := resumeStream(filename, c); err == nil {
+				return o, c.Next, nil
+			}
+		}
+	}
+
+	if err := removeCheckpoint(filename); err != nil {
+		return nil, 0, err
+	}
+	w, err := dxx.OpenAppend(partName(filename))
+	if err != nil {
+		return nil, 0, err
+	}
+	o := &overlapAddWriter{w: w, skip: skip, name: filename, lastSaved: time.Now()}
+	if dt.IsBinary() {
+		o.ckpt = &checkpoint{Params: params, DataType: dt.String()}
+	}
+	return o, 0, nil
+}
+
+// resumeStream restores the state of an interrupted rendering from the checkpoint.
+// The samples written after the checkpoint are discarded, including a sample torn by a crash.
+func resumeStream(filename string, c *checkpoint) (*overlapAddWriter, error) {
+	dt, err := dxx.StringToDataType(c.DataType)
+	if err != nil {
+		return nil, err
+	}
+	if !dt.IsBinary() {
+		// the part file cannot be cut at a sample.
+		return nil, dxx.ErrNotBinary
+	}
+	part := partName(filename)
+	info, err := os.Stat(part)
+	if err != nil {
+		return nil, err
+	}
+	if size := int64(c.Written) * int64(dt.ByteLen()); info.Size() > size {
+		if err := os.Truncate(part, size); err != nil {
+			return nil, err
+		}
+	}
+	w, err := dxx.OpenAppend(part)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Truncate(c.Written); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return &overlapAddWriter{
+		w:         w,
+		skip:      c.Skip,
+		buf:       c.pending(),
+		off:       c.Off,
+		n:         c.Written,
+		ckpt:      c,
+		name:      filename,
+		lastSaved: time.Now(),
+		saved:     true,
+	}, nil
+}